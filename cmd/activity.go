@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sett4/duckhist/internal/analytics"
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// activityCmd represents the activity command
+var activityCmd = &cobra.Command{
+	Use:   "activity",
+	Short: "Show command activity over time",
+	Long: `Show how many commands were executed over time.
+The default output is a calendar-style heatmap of the number of commands per day.
+With --format csv or --format json, a time series bucketed by hour, day or week is printed instead.`,
+	RunE: runActivity,
+}
+
+var (
+	activityFormat    string
+	activityPeriod    string
+	activityDirPrefix string
+	activityHost      string
+	activityWeeks     int
+	activityColor     string
+)
+
+func init() {
+	activityCmd.Flags().StringVarP(&activityFormat, "format", "f", "heatmap", "output format (heatmap, csv, json)")
+	activityCmd.Flags().StringVar(&activityPeriod, "by", "day", "time-series bucket for csv and json output (hour, day, week)")
+	activityCmd.Flags().StringVar(&activityDirPrefix, "dir-prefix", "", "only count commands executed in this directory or below it")
+	activityCmd.Flags().StringVar(&activityHost, "host", "", "only count commands executed on this host")
+	activityCmd.Flags().IntVar(&activityWeeks, "weeks", 52, "number of weeks shown in the heatmap")
+	activityCmd.Flags().StringVar(&activityColor, "color", "auto", "colorize the heatmap (auto, always, never)")
+	rootCmd.AddCommand(activityCmd)
+}
+
+func runActivity(cmd *cobra.Command, args []string) error {
+	period, err := analytics.ParsePeriod(activityPeriod)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	q := manager.Query()
+	if activityDirPrefix != "" {
+		dir, err := filepath.Abs(activityDirPrefix)
+		if err != nil {
+			return fmt.Errorf("failed to resolve directory: %w", err)
+		}
+		q.UnderDirectory(dir)
+	}
+	if activityHost != "" {
+		q.OnHost(activityHost)
+	}
+
+	// Commands are counted as they are read, so the history is not held in memory
+	out := cmd.OutOrStdout()
+	switch activityFormat {
+	case "heatmap":
+		color, err := useColor(activityColor, out)
+		if err != nil {
+			return err
+		}
+		heatmap, err := analytics.NewHeatmap(activityWeeks, time.Now())
+		if err != nil {
+			return err
+		}
+		err = q.Since(heatmap.Start()).Each(func(entry history.Entry) error {
+			heatmap.Add(entry.Timestamp)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get history: %w", err)
+		}
+		return heatmap.Render(out, color)
+	case "csv", "json":
+		counter := analytics.NewActivityCounter(period, time.Local)
+		err := q.Each(func(entry history.Entry) error {
+			counter.Add(entry.Timestamp)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get history: %w", err)
+		}
+		if activityFormat == "csv" {
+			return writeActivityCSV(out, counter.Points())
+		}
+		return writeActivityJSON(out, counter.Points())
+	default:
+		return fmt.Errorf("unknown format %q (expected heatmap, csv or json)", activityFormat)
+	}
+}
+
+// useColor decides whether to emit ANSI colors based on the --color flag value
+func useColor(mode string, out io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		f, ok := out.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == "", nil
+	default:
+		return false, fmt.Errorf("unknown color mode %q (expected auto, always or never)", mode)
+	}
+}
+
+func writeActivityCSV(w io.Writer, points []analytics.ActivityPoint) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"start", "count"}); err != nil {
+		return err
+	}
+	for _, point := range points {
+		if err := writer.Write([]string{point.Start.Format(time.RFC3339), strconv.Itoa(point.Count)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeActivityJSON(w io.Writer, points []analytics.ActivityPoint) error {
+	if points == nil {
+		points = []analytics.ActivityPoint{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(points)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestRunActivity(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q", dbPath)), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	testCommands := []struct {
+		command   string
+		directory string
+		hostname  string
+	}{
+		{"make", "/work/project", "host1"},
+		{"make test", "/work/project/sub", "host1"},
+		{"ls", "/work/projectx", "host1"},
+		{"git pull", "/work/project", "host2"},
+	}
+	for _, tc := range testCommands {
+		if _, err := manager.AddCommand(tc.command, tc.directory, "", "", tc.hostname, "testuser", day, true); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	cfgFile = configPath
+	defer func() {
+		activityFormat, activityPeriod, activityDirPrefix, activityHost = "heatmap", "day", "", ""
+	}()
+
+	tests := []struct {
+		name      string
+		dirPrefix string
+		host      string
+		expected  string
+	}{
+		{"all", "", "", ",4\n"},
+		{"directory prefix", "/work/project", "", ",3\n"},
+		{"host", "", "host1", ",3\n"},
+		{"directory prefix and host", "/work/project", "host1", ",2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activityFormat, activityPeriod, activityDirPrefix, activityHost = "csv", "day", tt.dirPrefix, tt.host
+
+			var buf bytes.Buffer
+			activityCmd.SetOut(&buf)
+			if err := runActivity(activityCmd, nil); err != nil {
+				t.Fatalf("runActivity failed: %v", err)
+			}
+			if !strings.HasPrefix(buf.String(), "start,count\n2024-03-01T00:00:00") {
+				t.Errorf("unexpected csv output: %q", buf.String())
+			}
+			if !strings.HasSuffix(buf.String(), tt.expected) {
+				t.Errorf("expected output ending with %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
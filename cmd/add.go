@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/daemon"
 	"github.com/sett4/duckhist/internal/history"
 
 	"github.com/spf13/cobra"
@@ -19,12 +21,17 @@ var (
 	verbose    bool
 	workingDir string
 	noDedup    bool
+	noDaemon   bool
 )
 
+// daemonTimeout is how long add waits for the daemon before giving up
+const daemonTimeout = 2 * time.Second
+
 // CommandAdder handles adding commands to history
 type CommandAdder struct {
-	config  string
-	verbose bool
+	config   string
+	verbose  bool
+	noDaemon bool
 }
 
 // NewCommandAdder creates a new CommandAdder instance
@@ -51,7 +58,33 @@ func (ca *CommandAdder) AddCommand(command string, directory string, tty string,
 		return false, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	manager, err := history.NewManagerReadWrite(cfg.DatabasePath)
+	executedAt := time.Now()
+
+	// Prefer the daemon, which keeps the database open, and fall back to
+	// writing directly if it is not running
+	if !ca.noDaemon {
+		isDup, err := daemon.Add(cfg.DaemonSocket, daemon.Request{
+			Command:    command,
+			Directory:  directory,
+			TTY:        tty,
+			SID:        sid,
+			Hostname:   hostname,
+			Username:   username,
+			ExecutedAt: executedAt,
+			NoDedup:    noDedup,
+		}, daemonTimeout)
+		if err == nil {
+			if !isDup && ca.verbose {
+				fmt.Printf("Command added to history via daemon: %s\n", command)
+			}
+			return isDup, nil
+		}
+		if !errors.Is(err, daemon.ErrNotRunning) {
+			return false, fmt.Errorf("failed to add command via daemon: %w", err)
+		}
+	}
+
+	manager, err := history.NewManagerReadWrite(cfg.DatabasePath, managerOptions(cfg)...)
 	if err != nil {
 		return false, fmt.Errorf("failed to create history manager: %w", err)
 	}
@@ -61,7 +94,7 @@ func (ca *CommandAdder) AddCommand(command string, directory string, tty string,
 		}
 	}()
 
-	isDup, err := manager.AddCommand(command, directory, tty, sid, hostname, username, time.Now(), noDedup)
+	isDup, err := manager.AddCommand(command, directory, tty, sid, hostname, username, executedAt, noDedup)
 	if err != nil {
 		return false, fmt.Errorf("failed to add command: %w", err)
 	}
@@ -100,7 +133,12 @@ var addCmd = &cobra.Command{
 			tty = os.Getenv("TTY")
 		}
 
+		if sid == "" {
+			sid = os.Getenv(sessionEnv)
+		}
+
 		adder := NewCommandAdder(cfgFile, verbose)
+		adder.noDaemon = noDaemon
 		isDup, err := adder.AddCommand(command, workingDir, tty, sid, hostname, username, noDedup)
 		if err != nil {
 			if err.Error() == "empty command" {
@@ -125,8 +163,9 @@ var addCmd = &cobra.Command{
 func init() {
 	addCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	addCmd.Flags().BoolVar(&noDedup, "no-dedup", false, "allow duplicate commands")
+	addCmd.Flags().BoolVar(&noDaemon, "no-daemon", false, "write to the database directly even if the daemon is running")
 	addCmd.Flags().StringVarP(&workingDir, "directory", "d", "", "directory to record (default is current directory)")
 	addCmd.Flags().StringVar(&tty, "tty", "", "TTY (default is $TTY)")
-	addCmd.Flags().StringVar(&sid, "sid", "", "Session ID")
+	addCmd.Flags().StringVar(&sid, "sid", "", "Session ID (default is $DUCKHIST_SID)")
 	rootCmd.AddCommand(addCmd)
 }
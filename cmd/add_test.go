@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/migrate"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -447,3 +448,58 @@ func TestAddCmd_TTY(t *testing.T) {
 		}
 	})
 }
+
+func TestCommandAdder_AutoMigrate(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	content := fmt.Sprintf("database_path = %q\nauto_migrate = true", dbPath)
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+
+	// The database does not exist yet and is created by the first add
+	adder := NewCommandAdder(configPath, false)
+	if _, err := adder.AddCommand("ls", tmpDir, "", "", "localhost", "testuser", false); err != nil {
+		t.Fatalf("AddCommand failed: %v", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+	entries, err := manager.Query().GetEntries()
+	if err != nil {
+		t.Fatalf("GetEntries failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Command != "ls" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+// BenchmarkAddStartup measures a single add without the daemon, including
+// loading the config and opening the database
+func BenchmarkAddStartup(b *testing.B) {
+	tmpDir := b.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q", dbPath)), 0644); err != nil {
+		b.Fatalf("failed to create config file: %v", err)
+	}
+	if _, _, err := migrate.Up(dbPath); err != nil {
+		b.Fatalf("failed to run migrations: %v", err)
+	}
+
+	adder := NewCommandAdder(configPath, false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := adder.AddCommand(fmt.Sprintf("echo %d", i), tmpDir, "", "", "localhost", "testuser", false); err != nil {
+			b.Fatalf("AddCommand failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/daemon"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run a background daemon for low-latency recording",
+	Long: `Run a daemon that keeps the history database open and listens on a Unix socket
+(daemon_socket in the config file, default is database_path + ".sock").
+The add command sends commands to the daemon if it is running, which avoids opening
+the database on every prompt, and falls back to writing directly otherwise.
+Commands received while a write is in progress are written together in one transaction.
+The daemon runs in the foreground until it receives SIGINT or SIGTERM.`,
+	RunE: runDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("listening on %s", cfg.DaemonSocket)
+	return daemon.NewServer(manager, cfg.DaemonSocket).Serve(ctx)
+}
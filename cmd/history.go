@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 
 	"github.com/sett4/duckhist/internal/config"
 	"github.com/sett4/duckhist/internal/history"
 
+	"github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
 )
 
@@ -18,26 +22,65 @@ var historyCmd = &cobra.Command{
 	Long: `Display command history in a format optimized for incremental search tools like peco and fzf.
 The output shows:
 - Last N commands executed in the current directory (N is configurable in settings)
-- Followed by the full command history from all directories`,
+- Followed by the full command history from all directories
+Every command is shown once, at its most prominent position.
+
+With --columns, every line has the tab-separated columns relative date,
+shortened directory and command, for fzf --delimiter '\t' --with-nth. The
+date and directory are colored with --color always, for fzf --ansi.
+With --null, entries end with a NUL character instead of a newline, so
+multi-line commands can be read by fzf --read0.
+--separator sets the line between the two parts (--separator= for none) and
+--limit the maximum number of commands.`,
+	Example: `  duckhist history --columns --color always --null --separator= |
+    fzf --read0 --ansi --delimiter '\t' --nth 3..`,
 	RunE: runHistory,
 }
 
 var (
-	historyDirFlag string
+	historyDirFlag       string
+	historyColumnsFlag   bool
+	historyColorFlag     string
+	historyNullFlag      bool
+	historySeparatorFlag string
+	historyLimitFlag     int
+)
+
+// errHistoryLimit stops reading the history once --limit commands have been written
+var errHistoryLimit = errors.New("limit reached")
+
+// Colors of the date and directory columns
+const (
+	historyDateColor = "\x1b[90m"
+	historyDirColor  = "\x1b[34m"
+	historyColorOff  = "\x1b[0m"
 )
 
 func init() {
 	historyCmd.Flags().StringVarP(&historyDirFlag, "directory", "d", "", "directory to show history for (default is current directory)")
+	historyCmd.Flags().BoolVar(&historyColumnsFlag, "columns", false, "show the relative date and directory before the command, separated by tabs")
+	historyCmd.Flags().StringVar(&historyColorFlag, "color", "auto", "color the columns: auto, always or never")
+	historyCmd.Flags().BoolVarP(&historyNullFlag, "null", "0", false, "end entries with a NUL character instead of a newline")
+	historyCmd.Flags().StringVar(&historySeparatorFlag, "separator", "---", "line between current directory and full history; empty for none")
+	historyCmd.Flags().IntVarP(&historyLimitFlag, "limit", "n", 0, "maximum number of commands to show (0 for all)")
 	rootCmd.AddCommand(historyCmd)
 }
 
 func runHistory(cmd *cobra.Command, args []string) error {
+	if historyLimitFlag < 0 {
+		return fmt.Errorf("invalid limit %d", historyLimitFlag)
+	}
+	color, err := useColor(historyColorFlag, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+
 	cfg, err := config.LoadConfig(cfgFile)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	manager, err := history.NewManagerReadOnly(cfg.DatabasePath)
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
 	if err != nil {
 		return fmt.Errorf("failed to create history manager: %w", err)
 	}
@@ -60,6 +103,7 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	limit := cfg.CurrentDirectoryHistLimit
 	currentDirHistory, err := manager.Query().
 		InDirectory(currentDir).
+		DistinctCommands().
 		Limit(limit).
 		OrderByCurrentDirFirst(currentDir).
 		GetEntries()
@@ -67,33 +111,80 @@ func runHistory(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get current directory history: %w", err)
 	}
 
-	// Get full history excluding current directory entries
-	fullHistory, err := manager.FindHistory(currentDir, nil)
-	if err != nil {
-		return fmt.Errorf("failed to get full history: %w", err)
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	out := &historyWriter{w: w, columns: historyColumnsFlag, color: color, limit: historyLimitFlag, terminator: "\n"}
+	if historyNullFlag {
+		out.terminator = "\x00"
 	}
 
-	// Keep track of printed commands to avoid duplicates
-	printedCommands := make(map[string]bool)
-
 	// Print current directory history
 	for _, entry := range currentDirHistory {
-		if !printedCommands[entry.Command] {
-			fmt.Printf("%s\n", entry.Command)
-			printedCommands[entry.Command] = true
+		if err := out.write(entry); err != nil {
+			if errors.Is(err, errHistoryLimit) {
+				break
+			}
+			return fmt.Errorf("failed to write history: %w", err)
 		}
 	}
 
 	// Add delimiter between current directory history and full history
-	fmt.Println("---")
+	if historySeparatorFlag != "" && !out.full() {
+		if _, err := io.WriteString(w, historySeparatorFlag+out.terminator); err != nil {
+			return fmt.Errorf("failed to write history: %w", err)
+		}
+	}
 
-	// Print full history, skipping duplicates
-	for _, entry := range fullHistory {
-		if !printedCommands[entry.Command] {
-			fmt.Printf("%s\n", entry.Command)
-			printedCommands[entry.Command] = true
+	// Print full history as it is read. Its distinct commands start with the
+	// ones of the current directory in the same order, so those are skipped.
+	if !out.full() {
+		err = manager.Query().
+			DistinctCommands().
+			OrderByCurrentDirFirst(currentDir).
+			Offset(len(currentDirHistory)).
+			Each(out.write)
+		if err != nil && !errors.Is(err, errHistoryLimit) {
+			return fmt.Errorf("failed to get full history: %w", err)
 		}
 	}
 
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
 	return nil
 }
+
+// historyWriter writes entries, up to limit commands unless limit is 0
+type historyWriter struct {
+	w          io.Writer
+	columns    bool
+	color      bool
+	limit      int
+	terminator string
+	written    int
+}
+
+// full reports whether the limit has been reached
+func (h *historyWriter) full() bool {
+	return h.limit > 0 && h.written >= h.limit
+}
+
+func (h *historyWriter) write(entry history.Entry) error {
+	if h.full() {
+		return errHistoryLimit
+	}
+	h.written++
+
+	if !h.columns {
+		_, err := io.WriteString(h.w, entry.Command+h.terminator)
+		return err
+	}
+	// Columns are padded so that they line up at the same tab stops
+	date := fmt.Sprintf("%-14s", humanize.Time(entry.Timestamp))
+	dir := fmt.Sprintf("%-20s", ShortenPath(entry.Directory, 20))
+	if h.color {
+		date = historyDateColor + date + historyColorOff
+		dir = historyDirColor + dir + historyColorOff
+	}
+	_, err := io.WriteString(h.w, date+"\t"+dir+"\t"+entry.Command+h.terminator)
+	return err
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q\ncurrent_directory_history_limit = 2", dbPath)), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	records := []struct {
+		command, dir string
+	}{
+		{"make", "/src/app"},
+		{"ls", "/tmp"},
+		{"git status", "/src/app"},
+		{"echo 'a\nb'", "/tmp"},
+		{"make", "/tmp"},
+	}
+	for i, r := range records {
+		if _, err := manager.AddCommand(r.command, r.dir, "", "", "localhost", "testuser", time.Now().Add(time.Duration(i-5)*time.Hour), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	cfgFile = configPath
+	reset := func() {
+		historyDirFlag = "/src/app"
+		historyColumnsFlag = false
+		historyColorFlag = "auto"
+		historyNullFlag = false
+		historySeparatorFlag = "---"
+		historyLimitFlag = 0
+	}
+	defer func() {
+		reset()
+		historyDirFlag = ""
+	}()
+
+	tests := []struct {
+		name     string
+		set      func()
+		expected string
+	}{
+		{"default", func() {}, "git status\nmake\n---\necho 'a\nb'\nls\n"},
+		{"no separator", func() { historySeparatorFlag = "" }, "git status\nmake\necho 'a\nb'\nls\n"},
+		{"custom separator", func() { historySeparatorFlag = "=====" }, "git status\nmake\n=====\necho 'a\nb'\nls\n"},
+		{"null", func() { historyNullFlag = true }, "git status\x00make\x00---\x00echo 'a\nb'\x00ls\x00"},
+		{"limit in current directory", func() { historyLimitFlag = 1 }, "git status\n"},
+		{"limit in full history", func() { historyLimitFlag = 3 }, "git status\nmake\n---\necho 'a\nb'\n"},
+		{"directory without history", func() { historyDirFlag = "/none" }, "---\nmake\necho 'a\nb'\ngit status\nls\n"},
+		{"columns", func() { historyColumnsFlag = true; historyLimitFlag = 1; historyDirFlag = "/tmp" },
+			"1 hour ago    \t/tmp                \tmake\n"},
+		{"colors", func() {
+			historyColumnsFlag = true
+			historyColorFlag = "always"
+			historyLimitFlag = 1
+			historyDirFlag = "/tmp"
+		},
+			"\x1b[90m1 hour ago    \x1b[0m\t\x1b[34m/tmp                \x1b[0m\tmake\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			tt.set()
+			var buf bytes.Buffer
+			historyCmd.SetOut(&buf)
+			if err := runHistory(historyCmd, nil); err != nil {
+				t.Fatalf("runHistory failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	t.Run("invalid limit", func(t *testing.T) {
+		reset()
+		historyLimitFlag = -1
+		if err := runHistory(historyCmd, nil); err == nil || !strings.Contains(err.Error(), "invalid limit") {
+			t.Errorf("expected invalid limit error, got %v", err)
+		}
+	})
+}
@@ -83,7 +83,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create history manager
-	manager, err := history.NewManagerReadWrite(cfg.DatabasePath)
+	manager, err := history.NewManagerReadWrite(cfg.DatabasePath, managerOptions(cfg)...)
 	if err != nil {
 		return fmt.Errorf("failed to create history manager: %w", err)
 	}
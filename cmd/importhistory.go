@@ -51,7 +51,7 @@ func runImportHistory(cmd *cobra.Command, args []string) error {
 	}
 	defer file.Close()
 
-	manager, err := history.NewManagerReadWrite(cfg.DatabasePath)
+	manager, err := history.NewManagerReadWrite(cfg.DatabasePath, managerOptions(cfg)...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize history manager: %w", err)
 	}
@@ -69,20 +69,18 @@ func (ic *InitConfig) InitializeDatabase() error {
 		return fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Connect to database and create table
-	manager, err := history.NewManagerReadWrite(cfg.DatabasePath)
+	// Run migrations to create necessary tables and indexes
+	if err := migrateDatabase(cfg); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	// Check that the database can be opened
+	manager, err := history.NewManagerReadWrite(cfg.DatabasePath, managerOptions(cfg)...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
-	defer func() {
-		if err := manager.Close(); err != nil {
-			log.Printf("failed to close manager: %v", err)
-		}
-	}()
-
-	// Run migrations to create necessary tables and indexes
-	if err := RunMigrations(cfg.DatabasePath); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	if err := manager.Close(); err != nil {
+		log.Printf("failed to close manager: %v", err)
 	}
 
 	fmt.Printf("Initialized database at: %s\n", cfg.DatabasePath)
@@ -100,9 +98,17 @@ func (ic *InitConfig) CreateZshIntegration() error {
 		return fmt.Errorf("failed to create Zsh integration script: %w", err)
 	}
 
+	fzfScriptPath := filepath.Join(filepath.Dir(ic.GetConfigPath()), "fzf-duckhist.zsh")
+	if err := os.WriteFile(fzfScriptPath, []byte(embedded.GetFzfWidgetScript()), 0644); err != nil {
+		return fmt.Errorf("failed to create fzf widget script: %w", err)
+	}
+
 	fmt.Println("\nTo integrate with Zsh, add the following line to your ~/.zshrc:")
 	fmt.Printf("source %s\n", scriptPath)
+	fmt.Println("\nTo select commands with fzf on Ctrl-R instead, also add:")
+	fmt.Printf("source %s\n", fzfScriptPath)
 	fmt.Printf("\nCreated Zsh integration script at: %s\n", scriptPath)
+	fmt.Printf("Created fzf widget script at: %s\n", fzfScriptPath)
 	return nil
 }
 
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"log"
+	"os"
+	"regexp"
+	"time"
 
 	"github.com/sett4/duckhist/internal/config"
 	"github.com/sett4/duckhist/internal/history"
@@ -13,17 +17,133 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List command history",
-	Long:  `List all commands in the history database in reverse chronological order.`,
-	RunE:  runList,
+	Long: `List all commands in the history database in reverse chronological order.
+With --session, only commands of the current shell session are listed.
+With --query, only commands matching a search query are listed. The query
+uses the same syntax as the search box of the search command:
+
+  keyword "quoted phrase"   commands containing all keywords
+  dir:PATH                  run in PATH or a subdirectory
+  host:NAME user:NAME       run on host NAME / by user NAME
+  session:SID               run in shell session SID
+  after:TIME before:TIME    run in a time range (2024-01-31, 2024-01-31T15:04,
+                            today, yesterday, 30m, 12h, 7d, 2w)
+  -TERM                     exclude commands matching TERM
+  A OR B                    commands matching A or B
+
+Keywords ignore case unless --case (or case in the [search] section of the
+config file) selects sensitive or smart matching, as in the search command.
+
+With --regex, only commands containing a match of a regular expression in Go
+(RE2) syntax are listed. Matching is case-sensitive unless the pattern starts
+with (?i).
+
+--dir, --host, --since and --until restrict the listed commands like the
+dir:, host:, after: and before: filters of a query. --limit lists at most
+that many commands and --reverse lists the oldest first.
+
+--format selects the output: plain (the default, fields separated by tabs),
+table, json, ndjson, csv, or a Go template executed for every entry with the
+fields .ID, .Command, .Timestamp, .Hostname, .Directory, .Username, .TTY and
+.SID. --fields selects the fields of the other formats from id, time,
+command, directory, host, user, tty and sid. --null ends every entry of the
+plain and template formats with a NUL character instead of a newline, so
+multi-line commands can be read by fzf --read0 or xargs -0.
+Commands are written as they are read from the database.`,
+	Example: `  duckhist list --query 'docker dir:~/src/app after:7d'
+  duckhist list --query 'make test OR go test -host:ci'
+  duckhist list --regex 'kubectl .*--context[= ]prod.* delete'
+  duckhist list --format table --fields time,directory,command --since yesterday
+  duckhist list --format '{{.Timestamp.Format "15:04"}} {{.Command}}' --dir ~/src --limit 20
+  duckhist list --null | fzf --read0`,
+	RunE: runList,
 }
 
+var (
+	listSessionFlag bool
+	listQueryFlag   string
+	listRegexFlag   string
+	listCaseFlag    string
+	listFormatFlag  string
+	listFieldsFlag  string
+	listLimitFlag   int
+	listReverseFlag bool
+	listSinceFlag   string
+	listUntilFlag   string
+	listDirFlag     string
+	listHostFlag    string
+	listNullFlag    bool
+)
+
 func runList(cmd *cobra.Command, args []string) error {
+	var fields []string
+	if listFieldsFlag != "" {
+		var err error
+		if fields, err = parseListFields(listFieldsFlag); err != nil {
+			return err
+		}
+	}
+	// Entries are written as they are read instead of being collected first
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	out, err := newListWriter(listFormatFlag, fields, listNullFlag, w)
+	if err != nil {
+		return err
+	}
+	if listLimitFlag < 0 {
+		return fmt.Errorf("invalid limit %d", listLimitFlag)
+	}
+	now := time.Now()
+	var since, until time.Time
+	if listSinceFlag != "" {
+		if since, err = history.ParseTime(listSinceFlag, now); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if listUntilFlag != "" {
+		if until, err = history.ParseTime(listUntilFlag, now); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	// Relative dir: paths in the query are resolved against the working directory
+	var filter *history.Filter
+	var dir string
+	if listQueryFlag != "" || listDirFlag != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		if listDirFlag != "" {
+			if dir, err = history.ResolveDir(listDirFlag, wd); err != nil {
+				return fmt.Errorf("invalid --dir: %w", err)
+			}
+		}
+		if listQueryFlag != "" {
+			if filter, err = history.ParseFilter(listQueryFlag, now, wd); err != nil {
+				return fmt.Errorf("invalid query: %w", err)
+			}
+		}
+	}
+	var re *regexp.Regexp
+	if listRegexFlag != "" {
+		re, err = regexp.Compile(listRegexFlag)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	}
+
 	cfg, err := config.LoadConfig(cfgFile)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	if filter != nil {
+		filter.Case, err = searchCaseMode(cfg, listCaseFlag)
+		if err != nil {
+			return err
+		}
+	}
 
-	manager, err := history.NewManagerReadOnly(cfg.DatabasePath)
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
 	if err != nil {
 		return fmt.Errorf("failed to create history manager: %w", err)
 	}
@@ -33,18 +153,65 @@ func runList(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	commands, err := manager.ListCommands()
-	if err != nil {
-		return fmt.Errorf("failed to list commands: %w", err)
+	q := manager.Query()
+	if listSessionFlag {
+		sid, err := currentSessionID()
+		if err != nil {
+			return err
+		}
+		q.InSession(sid)
+	}
+	if filter != nil {
+		q.Match(filter)
+	}
+	if re != nil {
+		q.Regexp(re)
+	}
+	if dir != "" {
+		q.UnderDirectory(dir)
+	}
+	if listHostFlag != "" {
+		q.OnHost(listHostFlag)
+	}
+	if !since.IsZero() {
+		q.Since(since)
+	}
+	if !until.IsZero() {
+		q.Until(until)
+	}
+	if listReverseFlag {
+		q.OrderByOldestFirst()
+	}
+	if listLimitFlag > 0 {
+		q.Limit(listLimitFlag)
 	}
 
-	for _, command := range commands {
-		fmt.Println(command)
+	if err := q.Each(out.write); err != nil {
+		return fmt.Errorf("failed to list commands: %w", err)
+	}
+	if err := out.flush(); err != nil {
+		return fmt.Errorf("failed to write commands: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write commands: %w", err)
 	}
 
 	return nil
 }
 
 func init() {
+	listCmd.Flags().BoolVar(&listSessionFlag, "session", false, "only list commands of the current shell session")
+	listCmd.Flags().StringVarP(&listQueryFlag, "query", "q", "", "only list commands matching a search query")
+	listCmd.Flags().StringVar(&listRegexFlag, "regex", "", "only list commands matching a regular expression")
+	listCmd.Flags().StringVar(&listCaseFlag, "case", "", "case matching of query keywords: insensitive, sensitive or smart (default from config)")
+	listCmd.Flags().StringVarP(&listFormatFlag, "format", "f", "plain", "output format: plain, table, json, ndjson, csv or a Go template such as '{{.Directory}}: {{.Command}}'")
+	listCmd.Flags().StringVar(&listFieldsFlag, "fields", "", "comma-separated fields to print: id, time, command, directory, host, user, tty, sid, or all")
+	listCmd.Flags().IntVarP(&listLimitFlag, "limit", "n", 0, "maximum number of commands to list (0 for all)")
+	listCmd.Flags().BoolVarP(&listReverseFlag, "reverse", "r", false, "list the oldest commands first")
+	listCmd.Flags().StringVar(&listSinceFlag, "since", "", "only list commands run at or after a time (2024-01-31, 2024-01-31T15:04, today, 7d, ...)")
+	listCmd.Flags().StringVar(&listUntilFlag, "until", "", "only list commands run before a time")
+	listCmd.Flags().StringVar(&listDirFlag, "dir", "", "only list commands run in a directory or its subdirectories")
+	listCmd.Flags().StringVar(&listHostFlag, "host", "", "only list commands run on a host")
+	listCmd.Flags().BoolVarP(&listNullFlag, "null", "0", false, "end commands with a NUL character instead of a newline, e.g. for fzf --read0")
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+// listFields are the fields list can print, in the order of --fields all
+var listFields = []string{"id", "time", "command", "directory", "host", "user", "tty", "sid"}
+
+// listDefaultFields are the fields printed in each format without --fields
+var listDefaultFields = map[string][]string{
+	"plain":  {"command"},
+	"table":  {"time", "directory", "command"},
+	"json":   listFields,
+	"ndjson": listFields,
+	"csv":    listFields,
+}
+
+// listFieldValue returns the value of field of entry as it is passed to an sqlWriter
+func listFieldValue(entry history.Entry, field string) interface{} {
+	switch field {
+	case "id":
+		return entry.ID
+	case "time":
+		return entry.Timestamp
+	case "command":
+		return entry.Command
+	case "directory":
+		return entry.Directory
+	case "host":
+		return entry.Hostname
+	case "user":
+		return entry.Username
+	case "tty":
+		return entry.TTY
+	default:
+		return entry.SID
+	}
+}
+
+// parseListFields parses a comma-separated list of field names; "all" selects every field
+func parseListFields(value string) ([]string, error) {
+	if value == "all" {
+		return listFields, nil
+	}
+	var fields []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, field := range listFields {
+			known = known || field == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown field %q (expected all or %s)", name, strings.Join(listFields, ", "))
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// listWriter writes entries in one output format as they are read
+type listWriter interface {
+	write(entry history.Entry) error
+	flush() error
+}
+
+// newListWriter returns a writer of format, which is a format name or a Go
+// template executed for every entry. fields is nil for the default fields of
+// the format. With null, entries end with a NUL character instead of a newline.
+func newListWriter(format string, fields []string, null bool, w io.Writer) (listWriter, error) {
+	terminator := "\n"
+	if null {
+		terminator = "\x00"
+	}
+
+	if strings.Contains(format, "{{") {
+		if fields != nil {
+			return nil, fmt.Errorf("--fields cannot be used with a template")
+		}
+		tmpl, err := template.New("list").Parse(format)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		return &listTemplateWriter{w: w, tmpl: tmpl, terminator: terminator}, nil
+	}
+
+	defaults, ok := listDefaultFields[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (expected plain, table, json, ndjson, csv or a Go template)", format)
+	}
+	if fields == nil {
+		fields = defaults
+	}
+	if null && format != "plain" {
+		return nil, fmt.Errorf("--null cannot be used with format %s", format)
+	}
+
+	var out sqlWriter
+	if format == "plain" {
+		out = &listPlainWriter{w: w, terminator: terminator}
+	} else {
+		var err error
+		if out, err = newSQLWriter(format, w); err != nil {
+			return nil, err
+		}
+	}
+	return &listRowWriter{out: out, fields: fields}, nil
+}
+
+// listRowWriter writes the selected fields of entries as rows of an sqlWriter
+type listRowWriter struct {
+	out    sqlWriter
+	fields []string
+	// started is set once the header has been written
+	started bool
+}
+
+func (l *listRowWriter) start() error {
+	if l.started {
+		return nil
+	}
+	l.started = true
+	return l.out.header(l.fields)
+}
+
+func (l *listRowWriter) write(entry history.Entry) error {
+	if err := l.start(); err != nil {
+		return err
+	}
+	values := make([]interface{}, len(l.fields))
+	for i, field := range l.fields {
+		values[i] = listFieldValue(entry, field)
+	}
+	return l.out.row(values)
+}
+
+func (l *listRowWriter) flush() error {
+	// An empty result still has a header, or [] in JSON
+	if err := l.start(); err != nil {
+		return err
+	}
+	return l.out.flush()
+}
+
+// listPlainWriter writes the values of a row separated by tabs without a header
+type listPlainWriter struct {
+	w          io.Writer
+	terminator string
+}
+
+func (p *listPlainWriter) header(columns []string) error {
+	return nil
+}
+
+func (p *listPlainWriter) row(values []interface{}) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = fmt.Sprint(sqlValue(v))
+	}
+	_, err := io.WriteString(p.w, strings.Join(cells, "\t")+p.terminator)
+	return err
+}
+
+func (p *listPlainWriter) flush() error {
+	return nil
+}
+
+// listTemplateWriter executes a template for every entry
+type listTemplateWriter struct {
+	w          io.Writer
+	tmpl       *template.Template
+	terminator string
+}
+
+func (t *listTemplateWriter) write(entry history.Entry) error {
+	if err := t.tmpl.Execute(t.w, entry); err != nil {
+		return err
+	}
+	_, err := io.WriteString(t.w, t.terminator)
+	return err
+}
+
+func (t *listTemplateWriter) flush() error {
+	return nil
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestList(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q", dbPath)), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	for i, sid := range []string{"s1", "s2", "s1"} {
+		if _, err := manager.AddCommand(fmt.Sprintf("echo %d", i), "/tmp", "", sid, "localhost", "testuser", time.Now(), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	cfgFile = configPath
+	defer func() {
+		listSessionFlag = false
+		listQueryFlag = ""
+		listRegexFlag = ""
+		listCaseFlag = ""
+	}()
+
+	tests := []struct {
+		name     string
+		session  bool
+		query    string
+		regex    string
+		expected string
+	}{
+		{"all", false, "", "", "echo 2\necho 1\necho 0\n"},
+		{"session", true, "", "", "echo 2\necho 0\n"},
+		{"query", false, "echo -2 OR session:s2", "", "echo 1\necho 0\n"},
+		{"query and session", true, "-2 dir:/tmp", "", "echo 0\n"},
+		{"regex", false, "", `^echo [12]$`, "echo 2\necho 1\n"},
+		{"regex and query", false, "-1", `^echo [12]$`, "echo 2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listSessionFlag = tt.session
+			listQueryFlag = tt.query
+			listRegexFlag = tt.regex
+			t.Setenv(sessionEnv, "s1")
+			var buf bytes.Buffer
+			listCmd.SetOut(&buf)
+			if err := runList(listCmd, nil); err != nil {
+				t.Fatalf("runList failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	t.Run("invalid query", func(t *testing.T) {
+		listQueryFlag = "after:soon"
+		if err := runList(listCmd, nil); err == nil || !strings.Contains(err.Error(), "invalid query") {
+			t.Errorf("expected invalid query error, got %v", err)
+		}
+	})
+
+	t.Run("case", func(t *testing.T) {
+		listQueryFlag = "ECHO"
+		listRegexFlag = ""
+		for caseMode, expected := range map[string]string{"insensitive": "echo 2\necho 1\necho 0\n", "smart": ""} {
+			listCaseFlag = caseMode
+			var buf bytes.Buffer
+			listCmd.SetOut(&buf)
+			if err := runList(listCmd, nil); err != nil {
+				t.Fatalf("runList failed: %v", err)
+			}
+			if buf.String() != expected {
+				t.Errorf("%s: expected %q, got %q", caseMode, expected, buf.String())
+			}
+		}
+
+		listCaseFlag = "upper"
+		if err := runList(listCmd, nil); err == nil || !strings.Contains(err.Error(), "invalid case mode") {
+			t.Errorf("expected invalid case mode error, got %v", err)
+		}
+		listCaseFlag = ""
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		listQueryFlag = ""
+		listRegexFlag = "echo ("
+		if err := runList(listCmd, nil); err == nil || !strings.Contains(err.Error(), "invalid regular expression") {
+			t.Errorf("expected invalid regular expression error, got %v", err)
+		}
+	})
+}
+
+func TestListFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q", dbPath)), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	records := []struct {
+		command, dir, host string
+	}{
+		{"make", "/src/app", "laptop"},
+		{"go test \\\n  ./...", "/src/app/sub", "laptop"},
+		{"ls", "/tmp", "server"},
+	}
+	for i, r := range records {
+		if _, err := manager.AddCommand(r.command, r.dir, "", "s1", r.host, "testuser", start.Add(time.Duration(i)*time.Hour), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	cfgFile = configPath
+	reset := func() {
+		listFormatFlag = "plain"
+		listFieldsFlag = ""
+		listLimitFlag = 0
+		listReverseFlag = false
+		listSinceFlag = ""
+		listUntilFlag = ""
+		listDirFlag = ""
+		listHostFlag = ""
+		listNullFlag = false
+	}
+	defer reset()
+
+	tests := []struct {
+		name     string
+		set      func()
+		expected string
+	}{
+		{"plain", func() {}, "ls\ngo test \\\n  ./...\nmake\n"},
+		{"null", func() { listNullFlag = true }, "ls\x00go test \\\n  ./...\x00make\x00"},
+		{"fields", func() { listFieldsFlag = "host,command"; listLimitFlag = 1 }, "server\tls\n"},
+		{"limit and reverse", func() { listLimitFlag = 2; listReverseFlag = true }, "make\ngo test \\\n  ./...\n"},
+		{"since and until", func() { listSinceFlag = "2024-05-01T10:00"; listUntilFlag = "2024-05-01T11:00" }, "go test \\\n  ./...\n"},
+		{"dir and host", func() { listDirFlag = "/src/app/"; listHostFlag = "laptop"; listReverseFlag = true }, "make\ngo test \\\n  ./...\n"},
+		{"table", func() { listFormatFlag = "table"; listFieldsFlag = "directory,command"; listDirFlag = "/src" },
+			"DIRECTORY     COMMAND\n/src/app/sub  go test \\\\n  ./...\n/src/app      make\n"},
+		{"csv", func() { listFormatFlag = "csv"; listFieldsFlag = "command,user"; listHostFlag = "server" },
+			"command,user\nls,testuser\n"},
+		{"json", func() { listFormatFlag = "json"; listFieldsFlag = "command"; listLimitFlag = 1 },
+			"[\n  {\"command\": \"ls\"}\n]\n"},
+		{"json without entries", func() { listFormatFlag = "json"; listHostFlag = "none" }, "[]\n"},
+		{"ndjson", func() { listFormatFlag = "ndjson"; listFieldsFlag = "host,command"; listHostFlag = "laptop" },
+			"{\"host\":\"laptop\",\"command\":\"go test \\\\\\n  ./...\"}\n{\"host\":\"laptop\",\"command\":\"make\"}\n"},
+		{"template", func() { listFormatFlag = "{{.Hostname}}: {{.Command}}"; listNullFlag = true; listLimitFlag = 1 }, "server: ls\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			tt.set()
+			var buf bytes.Buffer
+			listCmd.SetOut(&buf)
+			if err := runList(listCmd, nil); err != nil {
+				t.Fatalf("runList failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name     string
+		set      func()
+		expected string
+	}{
+		{"unknown format", func() { listFormatFlag = "yaml" }, `unknown format "yaml"`},
+		{"unknown field", func() { listFieldsFlag = "command,exit" }, `unknown field "exit"`},
+		{"null with csv", func() { listFormatFlag = "csv"; listNullFlag = true }, "--null cannot be used with format csv"},
+		{"fields with template", func() { listFormatFlag = "{{.Command}}"; listFieldsFlag = "id" }, "--fields cannot be used with a template"},
+		{"invalid template", func() { listFormatFlag = "{{.Command" }, "invalid template"},
+		{"invalid since", func() { listSinceFlag = "soon" }, "invalid --since"},
+		{"negative limit", func() { listLimitFlag = -1 }, "invalid limit"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			tt.set()
+			if err := runList(listCmd, nil); err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// navPageSize is the number of entries fetched at a time while looking for the requested offset
+const navPageSize = 100
+
+// navCmd represents the nav command
+var navCmd = &cobra.Command{
+	Use:   "nav",
+	Short: "Print one history line for Up/Down arrow navigation",
+	Long: `Print the N-th most recent distinct command starting with the given prefix.
+Commands executed in the current directory come first, followed by commands
+from other directories, like the initial order of the search command.
+With --session, only commands of the current shell session are considered.
+Nothing is printed when there are fewer than N matching commands.
+This is used by the Up/Down arrow widgets in the zsh integration script.`,
+	RunE: runNav,
+}
+
+var (
+	navDir     string
+	navOffset  int
+	navPrefix  string
+	navSession bool
+)
+
+func init() {
+	navCmd.Flags().StringVarP(&navDir, "directory", "d", "", "directory to navigate the history of (default is current directory)")
+	navCmd.Flags().IntVarP(&navOffset, "offset", "n", 1, "position of the command to print, 1 being the most recent")
+	navCmd.Flags().StringVarP(&navPrefix, "prefix", "p", "", "only consider commands starting with this prefix")
+	navCmd.Flags().BoolVar(&navSession, "session", false, "only consider commands of the current shell session")
+	rootCmd.AddCommand(navCmd)
+}
+
+func runNav(cmd *cobra.Command, args []string) error {
+	if navOffset < 1 {
+		return nil
+	}
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	currentDir, err := filepath.Abs(navDir)
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	q := manager.Query()
+	if navSession {
+		sid, err := currentSessionID()
+		if err != nil {
+			return err
+		}
+		q.InSession(sid)
+	}
+
+	command, err := navCommand(q, navPrefix, currentDir, navOffset)
+	if err != nil {
+		return fmt.Errorf("failed to navigate history: %w", err)
+	}
+	if command != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), command)
+	}
+	return nil
+}
+
+// navCommand returns the offset-th distinct command of q starting with prefix,
+// ordered with currentDir first. Commands equal to the prefix are skipped, since
+// recalling them would not change the buffer. Entries are fetched page by page
+// so that only as much history is read as needed.
+func navCommand(q *history.HistoryQuery, prefix string, currentDir string, offset int) (string, error) {
+	q.WithPrefix(prefix).OrderByCurrentDirFirst(currentDir).Limit(navPageSize)
+
+	seen := map[string]bool{prefix: true}
+	for start := 0; ; start += navPageSize {
+		entries, err := q.Offset(start).GetEntries()
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			if seen[entry.Command] {
+				continue
+			}
+			seen[entry.Command] = true
+			offset--
+			if offset == 0 {
+				return entry.Command, nil
+			}
+		}
+		if len(entries) < navPageSize {
+			return "", nil
+		}
+	}
+}
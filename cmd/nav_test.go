@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestNavCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	testCommands := []struct {
+		command   string
+		directory string
+	}{
+		{"make build", "/project"},
+		{"ls", "/other"},
+		{"make test", "/project"},
+		{"make test", "/other"},
+		{"make lint", "/other"},
+		{"ls", "/project"},
+	}
+	for _, tc := range testCommands {
+		if _, err := manager.AddCommand(tc.command, tc.directory, "", "", "localhost", "testuser", time.Now(), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+
+	tests := []struct {
+		prefix   string
+		offset   int
+		expected string
+	}{
+		{"", 1, "ls"},
+		{"", 2, "make test"},
+		{"", 3, "make build"},
+		{"", 4, "make lint"},
+		{"", 5, ""},
+		{"make", 1, "make test"},
+		{"make", 3, "make lint"},
+		{"make test", 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q at %d", tt.prefix, tt.offset), func(t *testing.T) {
+			command, err := navCommand(manager.Query(), tt.prefix, "/project", tt.offset)
+			if err != nil {
+				t.Fatalf("navCommand failed: %v", err)
+			}
+			if command != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, command)
+			}
+		})
+	}
+
+	t.Run("more entries than a page", func(t *testing.T) {
+		for i := 0; i < navPageSize+10; i++ {
+			if _, err := manager.AddCommand(fmt.Sprintf("echo %d", i), "/paged", "", "", "localhost", "testuser", time.Now(), false); err != nil {
+				t.Fatalf("failed to add command: %v", err)
+			}
+		}
+		command, err := navCommand(manager.Query(), "echo", "/paged", navPageSize+5)
+		if err != nil {
+			t.Fatalf("navCommand failed: %v", err)
+		}
+		if command != "echo 5" {
+			t.Errorf("expected echo 5, got %q", command)
+		}
+	})
+}
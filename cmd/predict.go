@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/sett4/duckhist/internal/analytics"
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// predictOrder is the number of previous commands used as context for predictions
+const predictOrder = 2
+
+// predictCmd represents the predict command
+var predictCmd = &cobra.Command{
+	Use:   "predict",
+	Short: "Predict the next command",
+	Long: `Predict which commands are likely to be run next after the given command(s).
+The prediction is based on the order of commands within past shell sessions,
+with commands previously run in the same directory weighted higher.
+Repeat --after to give more context, oldest command first.`,
+	RunE: runPredict,
+}
+
+var (
+	predictAfter []string
+	predictDir   string
+	predictLimit int
+)
+
+func init() {
+	predictCmd.Flags().StringArrayVar(&predictAfter, "after", nil, "previous command (repeat for more context, oldest first)")
+	predictCmd.Flags().StringVarP(&predictDir, "directory", "d", "", "directory to predict for (default is current directory)")
+	predictCmd.Flags().IntVarP(&predictLimit, "limit", "n", 5, "maximum number of predictions")
+	if err := predictCmd.MarkFlagRequired("after"); err != nil {
+		log.Printf("failed to mark after flag as required: %v", err)
+	}
+	rootCmd.AddCommand(predictCmd)
+}
+
+func runPredict(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	currentDir := predictDir
+	if currentDir == "" {
+		currentDir, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	predictor, err := newPredictor(manager)
+	if err != nil {
+		return err
+	}
+
+	for _, prediction := range predictor.Predict(predictAfter, currentDir, predictLimit) {
+		fmt.Fprintf(cmd.OutOrStdout(), "%5.1f%%\t%s\n", prediction.Probability*100, prediction.Command)
+	}
+	return nil
+}
+
+// newPredictor builds a prediction model from the whole history, adding
+// entries as they are read
+func newPredictor(manager *history.Manager) (*analytics.Predictor, error) {
+	predictor := analytics.NewPredictor(nil, predictOrder)
+	err := manager.Query().OrderByOldestFirst().Each(func(entry history.Entry) error {
+		predictor.Add(entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	return predictor, nil
+}
+
+// predictNext predicts the commands following the most recently recorded ones,
+// in the current shell session if there is one
+func predictNext(manager *history.Manager, currentDir string, limit int) ([]analytics.Prediction, error) {
+	predictor, err := newPredictor(manager)
+	if err != nil {
+		return nil, err
+	}
+
+	q := manager.Query()
+	if sid := os.Getenv(sessionEnv); sid != "" {
+		q.InSession(sid)
+	}
+	recent, err := q.Limit(predictOrder).GetEntries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent history: %w", err)
+	}
+	after := make([]string, len(recent))
+	for i, entry := range recent {
+		after[len(recent)-1-i] = entry.Command
+	}
+
+	return predictor.Predict(after, currentDir, limit), nil
+}
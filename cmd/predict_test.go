@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestPredictNext(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	sessions := map[string][]string{
+		"s1": {"go build ./...", "go test ./...", "git add -A"},
+		"s2": {"go build ./...", "go test ./...", "git add -A"},
+		"s3": {"go build ./...", "go vet ./..."},
+		"s4": {"go build ./..."},
+	}
+	for _, sid := range []string{"s1", "s2", "s3", "s4"} {
+		for _, command := range sessions[sid] {
+			if _, err := manager.AddCommand(command, "/src", "", sid, "localhost", "testuser", time.Now(), true); err != nil {
+				t.Fatalf("failed to add command: %v", err)
+			}
+		}
+	}
+
+	predictions, err := predictNext(manager, "/src", 2)
+	if err != nil {
+		t.Fatalf("predictNext failed: %v", err)
+	}
+	if len(predictions) != 2 {
+		t.Fatalf("expected 2 predictions, got %+v", predictions)
+	}
+	if predictions[0].Command != "go test ./..." || predictions[1].Command != "go vet ./..." {
+		t.Errorf("unexpected predictions: %+v", predictions)
+	}
+}
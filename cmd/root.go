@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
 	"github.com/spf13/cobra"
 )
 
@@ -11,8 +14,9 @@ var cfgFile string
 
 var rootCmd = &cobra.Command{
 	Use:   "duckhist",
-	Short: "A command history manager using DuckDB",
-	Long: `duckhist is a command history manager that stores command history in DuckDB.
+	Short: "A command history manager using SQLite or DuckDB",
+	Long: `duckhist is a command history manager that stores command history in SQLite
+or, with storage_backend = "duckdb", in DuckDB.
 Command history is stored with additional context like the working directory,
 allowing for more intelligent history search and filtering.`,
 }
@@ -27,6 +31,15 @@ func initConfig() {
 	// Config initialization is handled in the commands that need it
 }
 
+// managerOptions returns the options for opening the history database configured in cfg
+func managerOptions(cfg *config.Config) []history.Option {
+	return []history.Option{
+		history.WithBackend(cfg.StorageBackend),
+		history.WithAutoMigrate(cfg.AutoMigrate),
+		history.WithSessionDedup(cfg.DedupPerSession),
+	}
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -5,52 +5,38 @@ import (
 	"log"
 
 	"github.com/sett4/duckhist/internal/config"
-	"github.com/sett4/duckhist/internal/embedded"
+	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/migrate"
 
-	_ "github.com/sett4/duckhist/internal/migrate"
-
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/spf13/cobra"
 )
 
 // RunMigrations applies database migrations to the specified database
 func RunMigrations(dbPath string) error {
-	// Create source driver from embedded filesystem
-	sourceDriver, err := iofs.New(embedded.GetMigrationsFS(), "migrations")
+	version, dirty, err := migrate.Up(dbPath)
 	if err != nil {
-		return fmt.Errorf("failed to create source driver: %w", err)
+		return err
 	}
 
-	// Create migration instance
-	m, err := migrate.NewWithSourceInstance("iofs", sourceDriver, fmt.Sprintf("sqlite3://%s", dbPath))
-	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
-	}
-	defer func() {
-		sourceErr, dbErr := m.Close()
-		if sourceErr != nil {
-			log.Printf("failed to close source: %v", sourceErr)
-		}
-		if dbErr != nil {
-			log.Printf("failed to close database: %v", dbErr)
-		}
-	}()
+	fmt.Printf("Database schema is up to date\n")
+	fmt.Printf("Database path: %s\n", dbPath)
+	fmt.Printf("Schema version: %d (dirty: %v)\n", version, dirty)
+	return nil
+}
 
-	// Apply all up migrations
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to apply migrations: %w", err)
+// migrateDatabase applies database migrations to the database configured in cfg
+// using its storage backend
+func migrateDatabase(cfg *config.Config) error {
+	if cfg.StorageBackend == "" || cfg.StorageBackend == history.DefaultBackend {
+		return RunMigrations(cfg.DatabasePath)
 	}
-
-	// Get current version
-	version, dirty, err := m.Version()
-	if err != nil {
-		return fmt.Errorf("failed to get schema version: %w", err)
+	if err := history.Migrate(cfg.StorageBackend, cfg.DatabasePath); err != nil {
+		return err
 	}
 
 	fmt.Printf("Database schema is up to date\n")
-	fmt.Printf("Database path: %s\n", dbPath)
-	fmt.Printf("Schema version: %d (dirty: %v)\n", version, dirty)
+	fmt.Printf("Database path: %s\n", cfg.DatabasePath)
+	fmt.Printf("Storage backend: %s\n", cfg.StorageBackend)
 	return nil
 }
 
@@ -66,7 +52,7 @@ var schemaMigrateCmd = &cobra.Command{
 		}
 
 		// Run migrations
-		if err := RunMigrations(cfg.DatabasePath); err != nil {
+		if err := migrateDatabase(cfg); err != nil {
 			log.Fatal(err)
 		}
 	},
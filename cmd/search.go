@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/sett4/duckhist/internal/config"
 	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/shell"
 
 	"github.com/dustin/go-humanize"
 	"github.com/gdamore/tcell/v2"
@@ -25,16 +29,70 @@ var searchCmd = &cobra.Command{
 The initial view shows:
 - Commands executed in the current directory
 - Followed by commands from all other directories
-As you type, the list will be filtered to match your search query.`,
+As you type, the list will be filtered to match your search query.
+Besides keywords, the query accepts field filters such as dir:~/src,
+host:NAME, user:NAME, session:SID, after:7d and before:2024-01-31,
+terms negated with a leading -, and alternatives separated by OR.
+Ctrl-R switches to a regular expression in Go (RE2) syntax instead; --regex
+starts in that mode.
+Keywords ignore case by default. --case (or case in the [search] section of
+the config file) selects insensitive, sensitive or smart, which matches a
+keyword case-sensitively only if it contains an uppercase letter. Ctrl-T
+cycles through the modes.
+Ctrl-O toggles a preview pane with the full command and all recorded details
+of the selected entry; --preview shows it from the start.
+Ctrl-S cycles the scope of the search between all history, the current
+directory, the current directory tree, the current shell session, the current
+host and the current git repository; --scope selects the initial scope and
+--session is short for --scope session.
+Space (after moving with the arrow keys or before typing) or Ctrl-Space marks
+entries. Enter then outputs the marked commands joined with &&, Alt-E outputs
+them the same way for editing before they are run, Ctrl-L outputs them on
+separate lines, Ctrl-X saves them as a shell script and Ctrl-D deletes
+them from the history. Without marks, these act on the selected entry.
+Tab outputs the selected command after a cd to its directory, quoted for the
+shell given with --shell or $SHELL. --output widget prints the action on the
+first line for shell widgets and --output json prints the action, the command
+line and the selected entries as a JSON object.
+--height draws the interface on that many lines below the prompt instead of
+the whole terminal, and --query fills in the search box.
+With --predict, the commands most likely to follow the last recorded command
+are shown below the history before anything is typed.`,
 	RunE: runSearch,
 }
 
 var (
-	searchDirFlag string
+	searchDirFlag     string
+	searchPredictFlag bool
+	searchSessionFlag bool
+	searchRegexFlag   bool
+	searchCaseFlag    string
+	searchPreviewFlag bool
+	searchScopeFlag   string
+	searchOutputFlag  string
+	searchShellFlag   string
+	searchHeightFlag  int
+	searchQueryFlag   string
 )
 
+// searchPredictLimit is the number of predicted commands shown with --predict
+const searchPredictLimit = 3
+
+// searchDebounce is how long typing has to pause before the search is run
+const searchDebounce = 100 * time.Millisecond
+
 func init() {
 	searchCmd.Flags().StringVarP(&searchDirFlag, "directory", "d", "", "directory to search history for (default is current directory)")
+	searchCmd.Flags().BoolVar(&searchPredictFlag, "predict", false, "show predicted next commands before typing")
+	searchCmd.Flags().BoolVar(&searchSessionFlag, "session", false, "only search commands of the current shell session")
+	searchCmd.Flags().BoolVar(&searchRegexFlag, "regex", false, "start with the search box matching a regular expression")
+	searchCmd.Flags().StringVar(&searchCaseFlag, "case", "", "case matching of keywords: insensitive, sensitive or smart (default from config)")
+	searchCmd.Flags().BoolVar(&searchPreviewFlag, "preview", false, "show the preview pane of the selected entry")
+	searchCmd.Flags().StringVar(&searchScopeFlag, "scope", "all", "initial scope: all, directory, tree, session, host or repo")
+	searchCmd.Flags().StringVar(&searchOutputFlag, "output", "text", "output format: text, widget or json")
+	searchCmd.Flags().IntVar(&searchHeightFlag, "height", 0, "draw on this many lines below the prompt instead of the whole terminal (default from config)")
+	searchCmd.Flags().StringVarP(&searchQueryFlag, "query", "q", "", "initial text of the search box")
+	searchCmd.Flags().StringVar(&searchShellFlag, "shell", "", "shell to quote the output for: sh, bash, zsh, ksh, dash or fish (default from $SHELL)")
 	rootCmd.AddCommand(searchCmd)
 }
 
@@ -43,8 +101,38 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	caseMode, err := searchCaseMode(cfg, searchCaseFlag)
+	if err != nil {
+		return err
+	}
+	if err := checkSearchOutput(searchOutputFlag); err != nil {
+		return err
+	}
+	dialect := shell.DetectDialect()
+	if searchShellFlag != "" {
+		if dialect, err = shell.ParseDialect(searchShellFlag); err != nil {
+			return err
+		}
+	}
+	// Saved scripts replay the recorded command lines, which fish cannot run
+	scriptSh, err := scriptShell(searchShellFlag)
+	if err != nil {
+		scriptSh, _ = scriptShell("")
+	}
+	height, err := searchHeight(cfg, searchHeightFlag)
+	if err != nil {
+		return err
+	}
+	keymap, err := newSearchKeymap(cfg.Search.Keys)
+	if err != nil {
+		return err
+	}
+	theme, err := newSearchTheme(cfg.Search.Theme)
+	if err != nil {
+		return err
+	}
 
-	manager, err := history.NewManagerReadOnly(cfg.DatabasePath)
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
 	if err != nil {
 		return fmt.Errorf("failed to create history manager: %w", err)
 	}
@@ -63,12 +151,24 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Get initial history (all commands)
-	allHistory, err := manager.FindHistory(currentDir, nil)
+	// Every query is restricted to the selected scope
+	scopeName := searchScopeFlag
+	if searchSessionFlag {
+		// Report why the session scope is not available
+		if _, err := currentSessionID(); err != nil {
+			return err
+		}
+		scopeName = "session"
+	}
+	scopes := searchScopes(currentDir)
+	scopeIndex, err := findSearchScope(scopes, scopeName)
 	if err != nil {
-		return fmt.Errorf("failed to get history: %w", err)
+		return err
 	}
 
+	// Predicted commands are shown closest to the input field, i.e. first in the list
+	var predicted []history.Entry
+
 	// Create application
 	app := tview.NewApplication()
 
@@ -76,137 +176,564 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	table := tview.NewTable().
 		SetSelectable(true, false).
 		SetFixed(1, 0).
-		SetBorders(false)
+		SetBorders(false).
+		SetSelectedStyle(theme.selection)
 
 	// Create help text view
 	helpText := tview.NewTextView().
-		SetText("TAB: cd & cmd    ENTER: cmd    ESC: exit    Multiple keywords (AND) | \"quoted phrases\" supported").
 		SetTextAlign(tview.AlignCenter)
 
-	// Set table headers
-	table.SetCell(0, 0, tview.NewTableCell("Date").SetSelectable(false))
-	table.SetCell(0, 1, tview.NewTableCell("Directory").SetSelectable(false))
-	table.SetCell(0, 2, tview.NewTableCell("Command").SetSelectable(false))
-
 	// Create input field for search
 	input := tview.NewInputField().
-		SetLabel("Search: ").
-		SetFieldWidth(0)
+		SetFieldWidth(0).
+		SetText(searchQueryFlag)
+
+	// In regex mode the search box holds a regular expression instead of a query
+	regexMode := searchRegexFlag
+	setMode := func() {
+		scope := scopes[scopeIndex].name
+		if detail := scopes[scopeIndex].detail; detail != "" {
+			scope += " " + detail
+		}
+		markHelp := keymap.help(actionMark, "mark")
+		if markHelp == "" {
+			markHelp = keymap.help(actionToggleMark, "mark")
+		}
+		help := []string{
+			"Scope: " + scope,
+			keymap.help(actionCycleScope, "scope"),
+			keymap.help(actionAcceptCd, "cd & cmd"),
+			keymap.help(actionAccept, "cmd"),
+			keymap.help(actionEdit, "edit"),
+			markHelp,
+			keymap.help(actionTogglePreview, "preview"),
+			keymap.help(actionAbort, "exit"),
+		}
+		if regexMode {
+			help = append(help, keymap.help(actionToggleRegex, "query mode"), "Go regular expression, (?i) ignores case")
+			input.SetLabel("Regex: ")
+		} else {
+			help = append(help, keymap.help(actionToggleRegex, "regex"), keymap.help(actionCycleCase, "case"), `Keywords "phrases" dir: host: after: -not OR`)
+			input.SetLabel(fmt.Sprintf("Search (%s): ", caseMode))
+		}
+		helpText.SetText(joinHelp(help))
+	}
+	setMode()
+
+	// Number of matching entries, shown next to the input field
+	countView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignRight)
 
 	// Create layout with table on top and input at bottom
+	inputRow := tview.NewFlex().
+		AddItem(input, 0, 1, true).
+		AddItem(countView, searchStatusWidth, 0, false)
+	// The preview pane is shown next to the table when toggled on
+	preview := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true)
+	preview.SetBorder(true).SetTitle(" Preview ")
+	body := tview.NewFlex().
+		AddItem(table, 0, 1, false)
 	flex := tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(helpText, 1, 0, false).
-		AddItem(table, 0, 1, false).
-		AddItem(input, 1, 0, true)
-
-	// Function to update table based on search query
-	updateTable := func(query string) {
-		// Clear table except headers
-		table.Clear()
-		table.SetCell(0, 0, tview.NewTableCell("Date").SetSelectable(false))
-		table.SetCell(0, 1, tview.NewTableCell("Directory").SetSelectable(false))
-		table.SetCell(0, 2, tview.NewTableCell("Command").SetSelectable(false))
-
-		var entries []history.Entry
-		var err error
+		AddItem(body, 0, 1, false).
+		AddItem(inputRow, 1, 0, true)
+
+	// Dialogs are shown on top of the search view
+	pages := tview.NewPages().
+		AddPage("search", flex, true, true)
+	showDialog := func(name string, dialog tview.Primitive, width, height int) {
+		pages.AddPage(name, tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(dialog, height, 0, true).
+				AddItem(nil, 0, 1, false), width, 0, true).
+			AddItem(nil, 0, 1, false), true, true)
+		app.SetFocus(dialog)
+	}
+	closeDialog := func(name string) {
+		pages.RemovePage(name)
+		app.SetFocus(input)
+	}
 
-		if query == "" {
-			entries = allHistory
-		} else {
-			entries, err = manager.FindByCommand(query, currentDir)
-			if err != nil {
-				// Just use empty list if there's an error
-				entries = []history.Entry{}
-			}
+	// showError shows err in place of the count, which is widened to fit the message
+	showError := func(err error) {
+		inputRow.ResizeItem(countView, 0, 1)
+		countView.SetText(fmt.Sprintf("[red]%v", err))
+	}
+	showStatus := func(text string) {
+		inputRow.ResizeItem(countView, searchStatusWidth, 0)
+		countView.SetText(text)
+	}
+
+	// results and marks are only accessed from the UI goroutine
+	var results *searchResults
+	marks := newSearchMarks()
+
+	// matchStatus returns the number of matching and marked entries
+	matchStatus := func() string {
+		status := fmt.Sprintf("%s matches", humanize.Comma(int64(results.total)))
+		if marks.Len() > 0 {
+			status += fmt.Sprintf(", %d marked", marks.Len())
 		}
+		return status
+	}
+	cancelSearch := context.CancelFunc(func() {})
 
-		// Add items in reverse order so that newer commands appear at the bottom
-		for i := len(entries) - 1; i >= 0; i-- {
-			entry := entries[i]
-			row := len(entries) - i // Account for header row
+	// selected returns the entry of the selected row
+	selected := func() (history.Entry, bool) {
+		if results == nil {
+			return history.Entry{}, false
+		}
+		row, _ := table.GetSelection()
+		return results.EntryAtRow(row)
+	}
 
-			// Format date as relative time
-			dateStr := humanize.Time(entry.Timestamp)
+	// updatePreview shows the selected entry in the preview pane. The number of
+	// runs of a command is counted in the background the first time it is shown.
+	previewShown := false
+	runCounts := make(map[string]int)
+	cancelRuns := context.CancelFunc(func() {})
+	var updatePreview func()
+	updatePreview = func() {
+		if !previewShown {
+			return
+		}
+		entry, ok := selected()
+		if !ok {
+			preview.SetText("")
+			return
+		}
+		runs, counted := runCounts[entry.Command]
+		if !counted {
+			runs = -1
+		}
+		preview.SetText(formatPreview(entry, runs)).ScrollToBeginning()
+		if counted || entry.ID == "" {
+			return
+		}
 
-			// Shorten directory
-			dir := ShortenPath(entry.Directory, 20)
+		cancelRuns()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelRuns = cancel
+		go func() {
+			n, err := manager.Query().WithCommand(entry.Command).CountContext(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					n = -1 // Not retried
+				}
+				runCounts[entry.Command] = n
+				updatePreview()
+			})
+		}()
+	}
+	table.SetSelectionChangedFunc(func(row, column int) {
+		updatePreview()
+	})
 
-			// Add cells to the row
-			table.SetCell(row, 0, tview.NewTableCell(dateStr))
-			dirCell := tview.NewTableCell(dir)
-			dirCell.SetReference(entry.Directory) // Allow directory cell to expand
-			table.SetCell(row, 1, dirCell)
-			table.SetCell(row, 2, tview.NewTableCell(entry.Command))
+	// togglePreview shows or hides the preview pane
+	togglePreview := func() {
+		previewShown = !previewShown
+		if previewShown {
+			body.AddItem(preview, 0, 1, false)
+			updatePreview()
+		} else {
+			body.RemoveItem(preview)
 		}
+	}
 
-		if table.GetRowCount() > 1 {
-			table.Select(table.GetRowCount()-1, 0) // Select last row
+	// search replaces the table content with the entries matching text.
+	// Counting runs in the background and a previous search still running is canceled.
+	search := func(text string) {
+		// An invalid query keeps the previous results on screen
+		match := func(q *history.HistoryQuery) *history.HistoryQuery { return q }
+		var matched *regexp.Regexp
+		if regexMode {
+			if text != "" {
+				re, err := regexp.Compile(text)
+				if err != nil {
+					showError(err)
+					return
+				}
+				match = func(q *history.HistoryQuery) *history.HistoryQuery { return q.Regexp(re) }
+				matched = re
+			}
+		} else {
+			filter, err := history.ParseFilter(text, time.Now(), currentDir)
+			if err != nil {
+				showError(err)
+				return
+			}
+			filter.Case = caseMode
+			match = func(q *history.HistoryQuery) *history.HistoryQuery { return q.Match(filter) }
+			matched = keywordPattern(filter.Keywords(), caseMode)
+		}
+
+		cancelSearch()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelSearch = cancel
+		showStatus("searching...")
+
+		// Pages are loaded in the background with the scope of the search that
+		// started them, even after the scope has been cycled on the UI goroutine
+		scope := scopes[scopeIndex]
+		query := scope.query(manager, match, currentDir)
+		var shownPredictions []history.Entry
+		if text == "" {
+			shownPredictions = predicted
+		}
+		redraw := func() {
+			app.QueueUpdateDraw(func() {
+				if results != nil && results.ctx == ctx {
+					if err := results.Err(); err != nil {
+						showError(err)
+					}
+					updatePreview()
+				}
+			})
 		}
+
+		go func() {
+			newResults, err := newSearchResults(ctx, query, shownPredictions, redraw)
+			app.QueueUpdateDraw(func() {
+				// Another search has been started in the meantime
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					showError(err)
+					return
+				}
+				results = newResults
+				results.marks = marks
+				results.theme = theme
+				results.highlight = matched
+				table.SetContent(results)
+				if results.Len() > 0 {
+					table.Select(results.GetRowCount()-1, 0) // Select newest entry
+				}
+				updatePreview()
+				showStatus(matchStatus())
+			})
+		}()
 	}
 
 	// Initial population of the table
-	updateTable("")
+	table.SetContent(&searchResults{})
+	search(searchQueryFlag)
+
+	// The prediction model is built from the whole history, so it is loaded
+	// while the history is already shown and the predictions are added later
+	if searchPredictFlag {
+		go func() {
+			predictions, err := predictNext(manager, currentDir, searchPredictLimit)
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					showError(err)
+					return
+				}
+				for _, prediction := range predictions {
+					predicted = append(predicted, history.Entry{Command: prediction.Command, Directory: currentDir})
+				}
+				if len(predicted) > 0 && input.GetText() == "" {
+					search("")
+				}
+			})
+		}()
+	}
 
-	// Handle input changes
+	// Handle input changes, waiting for typing to pause before searching
+	var debounce *time.Timer
 	input.SetChangedFunc(func(text string) {
-		updateTable(text)
+		if debounce != nil {
+			debounce.Stop()
+		}
+		debounce = time.AfterFunc(searchDebounce, func() {
+			app.QueueUpdate(func() {
+				search(text)
+			})
+		})
 	})
 
+	if searchPreviewFlag {
+		togglePreview()
+	}
+
+	// targets returns the marked entries, or the selected entry if none is marked
+	targets := func() []history.Entry {
+		if marks.Len() > 0 {
+			return marks.Entries()
+		}
+		if entry, ok := selected(); ok {
+			return []history.Entry{entry}
+		}
+		return nil
+	}
+
+	// toggleMark marks the selected entry or removes its mark and moves to the next older entry
+	toggleMark := func() {
+		if entry, ok := selected(); ok && results != nil {
+			marks.Toggle(entry)
+			moveSelection(table, tcell.KeyUp)
+			showStatus(matchStatus())
+		}
+	}
+
+	// saveTargets asks for a file name and saves the targets there as a script
+	saveTargets := func() {
+		entries := recordedEntries(targets())
+		if len(entries) == 0 {
+			return
+		}
+		prompt := tview.NewInputField().
+			SetLabel("File: ").
+			SetText("duckhist-script.sh")
+		prompt.SetBorder(true).SetTitle(fmt.Sprintf(" Save %d commands as a script ", len(entries)))
+		prompt.SetDoneFunc(func(key tcell.Key) {
+			closeDialog("save")
+			if key != tcell.KeyEnter {
+				return
+			}
+			path := prompt.GetText()
+			if err := saveScript(path, scriptSh, entries); err != nil {
+				showError(err)
+				return
+			}
+			marks.Clear()
+			showStatus(fmt.Sprintf("saved to %s", path))
+		})
+		showDialog("save", prompt, 60, 3)
+	}
+
+	// deleteTargets asks for confirmation and deletes the targets from the history
+	deleteTargets := func() {
+		entries := recordedEntries(targets())
+		if len(entries) == 0 {
+			return
+		}
+		confirm := tview.NewModal().
+			SetText(fmt.Sprintf("Delete %d commands from the history?", len(entries))).
+			AddButtons([]string{"Cancel", "Delete"}).
+			SetDoneFunc(func(_ int, label string) {
+				closeDialog("delete")
+				if label != "Delete" {
+					return
+				}
+				ids := make([]string, len(entries))
+				for i, entry := range entries {
+					ids[i] = entry.ID
+				}
+				if _, err := deleteEntries(cfg, ids); err != nil {
+					showError(err)
+					return
+				}
+				marks.Clear()
+				clear(runCounts)
+				search(input.GetText())
+			})
+		pages.AddPage("delete", confirm, false, true)
+		app.SetFocus(confirm)
+	}
+
+	// The mark key marks entries while moving through the list or before
+	// anything is typed, and is typed into the search box otherwise
+	navigating := false
+
+	// selection is output once the application has stopped
+	var selection *searchSelection
+
 	// Set up key handling
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyTab:
-			// Output selected command and exit
-			if table.GetRowCount() > 1 {
-				row, _ := table.GetSelection()
-				command := table.GetCell(row, 2).Text
+		// Dialogs handle their own keys
+		if name, _ := pages.GetFrontPage(); name != "search" {
+			return event
+		}
 
+		wasNavigating := navigating
+		navigating = false
+		action, ok := keymap.action(event)
+		if !ok {
+			return event
+		}
+		switch action {
+		case actionMark:
+			// Typed into the search box while a query is being typed
+			if !wasNavigating && input.GetText() != "" {
+				return event
+			}
+			toggleMark()
+			navigating = true
+		case actionToggleMark:
+			toggleMark()
+			navigating = true
+		case actionAccept, actionAcceptLines, actionEdit:
+			// Output the marked commands or the selected command and exit
+			if entries := targets(); len(entries) > 0 {
+				selection = newSearchSelection(action, entries, dialect)
 				app.Stop()
-				dirRef, ok := table.GetCell(row, 1).GetReference().(string)
-				if !ok {
-					panic("failed to assert directory reference as string")
-				}
-				fmt.Println("cd " + dirRef + ";" + command)
 			}
-			return nil
-		case tcell.KeyEnter:
-			// Output selected command and exit (original behavior)
-			if table.GetRowCount() > 1 {
-				row, _ := table.GetSelection()
-				command := table.GetCell(row, 2).Text
+		case actionSaveScript:
+			saveTargets()
+		case actionDelete:
+			deleteTargets()
+		case actionAcceptCd:
+			// Output selected command with its directory and exit
+			if entry, ok := selected(); ok {
+				selection = newSearchSelection(action, []history.Entry{entry}, dialect)
 				app.Stop()
-				fmt.Println(command)
 			}
-			return nil
-		case tcell.KeyEsc:
+		case actionToggleRegex:
+			regexMode = !regexMode
+			setMode()
+			search(input.GetText())
+		case actionTogglePreview:
+			togglePreview()
+		case actionCycleScope:
+			scopeIndex = (scopeIndex + 1) % len(scopes)
+			setMode()
+			search(input.GetText())
+		case actionCycleCase:
+			if !regexMode {
+				caseMode = caseMode.Next()
+				setMode()
+				search(input.GetText())
+			}
+		case actionAbort:
 			// Just exit without output
 			app.Stop()
-			return nil
-		case tcell.KeyUp:
-			// Move selection up
-			row, _ := table.GetSelection()
-			if row > 1 { // Don't select header row
-				table.Select(row-1, 0)
-			}
-			return nil
-		case tcell.KeyDown:
-			// Move selection down
-			row, _ := table.GetSelection()
-			if row < table.GetRowCount()-1 {
-				table.Select(row+1, 0)
-			}
-			return nil
+		case actionUp:
+			moveSelection(table, tcell.KeyUp)
+			navigating = true
+		case actionDown:
+			moveSelection(table, tcell.KeyDown)
+			navigating = true
+		case actionPageUp:
+			moveSelection(table, tcell.KeyPgUp)
+			navigating = true
+		case actionPageDown:
+			moveSelection(table, tcell.KeyPgDn)
+			navigating = true
 		}
-		return event
+		return nil
 	})
 
+	if height > 0 {
+		screen, err := newInlineScreen(height)
+		if err != nil {
+			return err
+		}
+		// A terminal too small for the inline screen is taken over as a whole
+		if screen != nil {
+			app.SetScreen(screen)
+		}
+	}
+
 	// Run application
-	if err := app.SetRoot(flex, true).Run(); err != nil {
+	err = app.SetRoot(pages, true).Run()
+	cancelSearch()
+	cancelRuns()
+	if err != nil {
 		return fmt.Errorf("application error: %w", err)
 	}
 
-	return nil
+	if selection == nil {
+		return nil
+	}
+	return writeSearchSelection(cmd.OutOrStdout(), searchOutputFlag, selection)
+}
+
+// joinHelp joins the parts of the help bar, leaving out empty ones for unbound actions
+func joinHelp(parts []string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "    ")
+}
+
+// searchStatusWidth is the width of the number of matches next to the input field
+const searchStatusWidth = 28
+
+// recordedEntries returns entries without predicted ones, which are not in the history
+func recordedEntries(entries []history.Entry) []history.Entry {
+	var recorded []history.Entry
+	for _, entry := range entries {
+		if entry.ID != "" {
+			recorded = append(recorded, entry)
+		}
+	}
+	return recorded
+}
+
+// searchMinHeight is the smallest height of the search interface: the help bar,
+// the table header, one entry and the search box
+const searchMinHeight = 4
+
+// searchHeight returns the height given by flag, or by the config if flag is 0.
+// 0 means the whole terminal.
+func searchHeight(cfg *config.Config, flag int) (int, error) {
+	height := flag
+	if height == 0 {
+		height = cfg.Search.Height
+	}
+	if height != 0 && height < searchMinHeight {
+		return 0, fmt.Errorf("invalid height %d: must be at least %d", height, searchMinHeight)
+	}
+	return height, nil
+}
+
+// searchCaseMode returns the case mode named by flag, or by the config if flag is empty
+func searchCaseMode(cfg *config.Config, flag string) (history.CaseMode, error) {
+	name := flag
+	if name == "" {
+		name = cfg.Search.Case
+	}
+	mode, err := history.ParseCaseMode(name)
+	if err != nil {
+		return mode, fmt.Errorf("invalid case mode: %w", err)
+	}
+	return mode, nil
+}
+
+// moveSelection moves the selected row of the search table by one row or one
+// screen, never selecting the header row
+func moveSelection(table *tview.Table, key tcell.Key) {
+	row, _ := table.GetSelection()
+	_, _, _, height := table.GetInnerRect()
+	page := height - 1 // without the header row
+	if page < 1 {
+		page = 1
+	}
+
+	switch key {
+	case tcell.KeyUp:
+		row--
+	case tcell.KeyDown:
+		row++
+	case tcell.KeyPgUp:
+		row -= page
+	case tcell.KeyPgDn:
+		row += page
+	}
+
+	if row > table.GetRowCount()-1 {
+		row = table.GetRowCount() - 1
+	}
+	if row < 1 { // Don't select header row
+		row = 1
+	}
+	if row < table.GetRowCount() {
+		table.Select(row, 0)
+	}
 }
 
 // ShortenPath converts
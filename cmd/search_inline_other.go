@@ -0,0 +1,14 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// newInlineScreen is not supported on platforms without /dev/tty
+func newInlineScreen(height int) (tcell.Screen, error) {
+	return nil, errors.New("--height is only supported on Unix")
+}
@@ -0,0 +1,276 @@
+//go:build unix
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/terminfo"
+)
+
+// cursorReport is the answer of the terminal to a cursor position request
+var cursorReport = regexp.MustCompile(`\x1b\[(\d+);(\d+)R`)
+
+// cursorReportTimeout is how long to wait for the terminal to report the cursor position
+const cursorReportTimeout = time.Second
+
+// newInlineScreen returns a screen drawn on the height lines below the cursor
+// instead of the alternate screen, so the output above stays visible. When the
+// screen is finalized, the lines are cleared and the cursor is put back.
+// It returns nil if the terminal is too small for height lines below the cursor.
+func newInlineScreen(height int) (tcell.Screen, error) {
+	ti, err := tcell.LookupTerminfo(os.Getenv("TERM"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up terminal: %w", err)
+	}
+	tty, err := tcell.NewDevTty()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open terminal: %w", err)
+	}
+	size, err := tty.WindowSize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get terminal size: %w", err)
+	}
+	// Keep the line of the cursor visible
+	height = min(height, size.Height-1)
+	if height < searchMinHeight {
+		if err := tty.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close terminal: %w", err)
+		}
+		return nil, nil
+	}
+
+	top, row, col, err := reserveLines(tty, height)
+	if err != nil {
+		return nil, err
+	}
+
+	inline := *ti
+	inline.EnterCA = ""
+	itty := &inlineTty{Tty: tty, ti: &inline, height: height, col: col, reports: make(chan cursorPosition, 1)}
+	itty.moveTo(top, row)
+	screen, err := tcell.NewTerminfoScreenFromTtyTerminfo(itty, &inline)
+	if err != nil {
+		return nil, err
+	}
+	itty.sync = screen.Sync
+	return screen, nil
+}
+
+// cursorPosition is a position reported by the terminal, counting from 1
+type cursorPosition struct {
+	row, col int
+}
+
+// reserveLines scrolls the terminal until there are height lines at the cursor.
+// The lines start at the line of the cursor if it is at the start of the line,
+// such as after running a command, and below it otherwise, such as in a shell
+// widget. It returns the first of the lines and the position of the cursor.
+func reserveLines(tty tcell.Tty, height int) (top, row, col int, err error) {
+	if err := tty.Start(); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to set up terminal: %w", err)
+	}
+
+	reports := make(chan cursorPosition, 2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var buf []byte
+		b := make([]byte, 64)
+		for {
+			n, err := tty.Read(b)
+			if err != nil {
+				return
+			}
+			buf = append(buf, b[:n]...)
+			for {
+				m := cursorReport.FindSubmatchIndex(buf)
+				if m == nil {
+					break
+				}
+				row, _ := strconv.Atoi(string(buf[m[2]:m[3]]))
+				col, _ := strconv.Atoi(string(buf[m[4]:m[5]]))
+				select {
+				case reports <- cursorPosition{row: row, col: col}:
+				default:
+				}
+				buf = buf[m[1]:]
+			}
+		}
+	}()
+	request := func(s string) (cursorPosition, error) {
+		if _, err := fmt.Fprintf(tty, "%s\x1b[6n", s); err != nil {
+			return cursorPosition{}, err
+		}
+		select {
+		case p := <-reports:
+			return p, nil
+		case <-time.After(cursorReportTimeout):
+			return cursorPosition{}, errors.New("terminal did not report the cursor position")
+		}
+	}
+
+	p, err := request("")
+	if err == nil {
+		lines := height
+		if p.col == 1 {
+			lines--
+		}
+		// The line feeds scroll the terminal if needed without changing the column in raw mode
+		p, err = request(fmt.Sprintf("%s\x1b[%dA", strings.Repeat("\n", lines), lines))
+	}
+	// Stopping also ends the read waiting for more reports
+	if stopErr := tty.Stop(); stopErr != nil && err == nil {
+		err = stopErr
+	}
+	<-done
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get cursor position: %w", err)
+	}
+
+	top = p.row + 1
+	if p.col == 1 {
+		top = p.row
+	}
+	return top, p.row, p.col, nil
+}
+
+// cursorAddress is a cursor movement written to the terminal
+var cursorAddress = regexp.MustCompile(`\x1b\[(\d+);\d+H`)
+
+// inlineTty is a terminal reporting only the lines of the inline screen as its height.
+// It keeps track of the line the screen has moved the cursor to, so that the
+// screen can follow its lines when the terminal moves them on a resize.
+type inlineTty struct {
+	tcell.Tty
+	// ti is the terminfo of the screen, whose cursor addressing depends on top.
+	// It is only changed by WindowSize, which the screen calls with its lock held.
+	ti     *terminfo.Terminfo
+	height int
+	// top is the first line of the screen, and row and col the cursor position to restore
+	top, row, col int
+	// sync redraws the whole screen
+	sync func()
+
+	mu sync.Mutex
+	// cursorRow is the line the cursor was last moved to, 0 before the first move
+	cursorRow int
+	// moved is how far the terminal has moved the lines of the screen since WindowSize
+	moved int
+	// pending is set while a cursor position report is expected in the input
+	pending atomic.Bool
+	reports chan cursorPosition
+}
+
+// moveTo moves the screen to start at line top, with the cursor restored to row
+func (t *inlineTty) moveTo(top, row int) {
+	t.top, t.row = max(top, 1), max(row, 1)
+	// Cursor addressing is moved down to the reserved lines, assuming an ANSI
+	// terminal. Clearing only clears the reserved lines.
+	t.ti.SetCursor = fmt.Sprintf("\x1b[%%i%%p1%%{%d}%%+%%d;%%p2%%dH", t.top-1)
+	t.ti.Clear = fmt.Sprintf("\x1b[%d;1H\x1b[J", t.top)
+	t.ti.ExitCA = fmt.Sprintf("\x1b[%d;%dH", t.row, t.col)
+}
+
+// WindowSize is called by the screen whenever it checks for a resize. The screen
+// moves along with its lines if the terminal has moved them, and up if it no
+// longer fits below its first line.
+func (t *inlineTty) WindowSize() (tcell.WindowSize, error) {
+	size, err := t.Tty.WindowSize()
+	if err != nil {
+		return size, err
+	}
+	t.mu.Lock()
+	moved := t.moved
+	t.moved = 0
+	t.mu.Unlock()
+
+	height := min(size.Height-1, t.height)
+	top, row := t.top+moved, t.row+moved
+	if overflow := top + height - 1 - size.Height; overflow > 0 {
+		top, row = top-overflow, row-overflow
+	}
+	if top != t.top || row != t.row {
+		t.moveTo(top, row)
+	}
+	size.Height = height
+	return size, nil
+}
+
+func (t *inlineTty) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if m := cursorAddress.FindAllSubmatch(b, -1); m != nil {
+		t.cursorRow, _ = strconv.Atoi(string(m[len(m)-1][1]))
+	}
+	return t.Tty.Write(b)
+}
+
+// Read removes a requested cursor position report from the input of the screen
+func (t *inlineTty) Read(b []byte) (int, error) {
+	n, err := t.Tty.Read(b)
+	if n > 0 && t.pending.Load() {
+		if m := cursorReport.FindSubmatchIndex(b[:n]); m != nil {
+			row, _ := strconv.Atoi(string(b[m[2]:m[3]]))
+			col, _ := strconv.Atoi(string(b[m[4]:m[5]]))
+			t.pending.Store(false)
+			select {
+			case t.reports <- cursorPosition{row: row, col: col}:
+			default:
+			}
+			n = m[0] + copy(b[m[0]:], b[m[1]:n])
+		}
+	}
+	return n, err
+}
+
+// follow asks the terminal where the cursor is now, to find out how far a resize
+// has moved the lines of the screen. Terminals that reflow their contents move
+// the lines down when they grow and bring back lines scrolled off the top.
+func (t *inlineTty) follow() {
+	t.mu.Lock()
+	from := t.cursorRow
+	t.pending.Store(true)
+	_, err := t.Tty.Write([]byte("\x1b[6n"))
+	t.mu.Unlock()
+	if err != nil {
+		t.pending.Store(false)
+		return
+	}
+
+	select {
+	case p := <-t.reports:
+		if from > 0 {
+			t.mu.Lock()
+			t.moved += p.row - from
+			t.mu.Unlock()
+		}
+	case <-time.After(cursorReportTimeout):
+		t.pending.Store(false)
+	}
+}
+
+// NotifyResize also follows the lines of the screen and redraws it on a resize,
+// since the screen may have moved even if its size is unchanged
+func (t *inlineTty) NotifyResize(cb func()) {
+	if cb == nil {
+		t.Tty.NotifyResize(nil)
+		return
+	}
+	t.Tty.NotifyResize(func() {
+		t.follow()
+		cb()
+		if t.sync != nil {
+			t.sync()
+		}
+	})
+}
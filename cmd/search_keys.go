@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// searchAction is something a key does in the search command
+type searchAction string
+
+const (
+	actionAccept        searchAction = "accept"
+	actionAcceptCd      searchAction = "accept-cd"
+	actionAcceptLines   searchAction = "accept-lines"
+	actionEdit          searchAction = "edit"
+	actionAbort         searchAction = "abort"
+	actionUp            searchAction = "up"
+	actionDown          searchAction = "down"
+	actionPageUp        searchAction = "page-up"
+	actionPageDown      searchAction = "page-down"
+	actionMark          searchAction = "mark"
+	actionToggleMark    searchAction = "toggle-mark"
+	actionSaveScript    searchAction = "save-script"
+	actionDelete        searchAction = "delete"
+	actionToggleRegex   searchAction = "toggle-regex"
+	actionCycleCase     searchAction = "cycle-case"
+	actionTogglePreview searchAction = "toggle-preview"
+	actionCycleScope    searchAction = "cycle-scope"
+)
+
+// searchDefaultKeys are the keys of every action unless the [search.keys]
+// section of the config file binds other keys to it
+var searchDefaultKeys = map[searchAction][]string{
+	actionAccept:        {"enter"},
+	actionAcceptCd:      {"tab"},
+	actionAcceptLines:   {"ctrl-l"},
+	actionEdit:          {"alt-e"},
+	actionAbort:         {"esc"},
+	actionUp:            {"up"},
+	actionDown:          {"down"},
+	actionPageUp:        {"pgup"},
+	actionPageDown:      {"pgdn"},
+	actionMark:          {"space"},
+	actionToggleMark:    {"ctrl-space"},
+	actionSaveScript:    {"ctrl-x"},
+	actionDelete:        {"ctrl-d"},
+	actionToggleRegex:   {"ctrl-r"},
+	actionCycleCase:     {"ctrl-t"},
+	actionTogglePreview: {"ctrl-o"},
+	actionCycleScope:    {"ctrl-s"},
+}
+
+// searchKey is a key press as matched against the key bindings.
+// Runes are only bound together with Alt, since plain runes are typed into the
+// search box; the only exception is Space.
+type searchKey struct {
+	key  tcell.Key
+	rune rune
+	alt  bool
+}
+
+// searchKeyNames maps lowercase key names to keys
+var searchKeyNames = func() map[string]tcell.Key {
+	names := map[string]tcell.Key{
+		"escape":    tcell.KeyEsc,
+		"shift-tab": tcell.KeyBacktab,
+		"pageup":    tcell.KeyPgUp,
+		"pagedown":  tcell.KeyPgDn,
+	}
+	for key, name := range tcell.KeyNames {
+		names[strings.ToLower(name)] = key
+	}
+	return names
+}()
+
+// parseSearchKey parses a key name such as "ctrl-p", "enter", "f2", "space" or "alt-j"
+func parseSearchKey(name string) (searchKey, error) {
+	lower := strings.ToLower(name)
+	if lower == "space" {
+		return searchKey{key: tcell.KeyRune, rune: ' '}, nil
+	}
+	if rest, ok := strings.CutPrefix(lower, "alt-"); ok {
+		if rest == "space" {
+			rest = " "
+		}
+		if utf8.RuneCountInString(rest) == 1 {
+			r, _ := utf8.DecodeRuneInString(rest)
+			return searchKey{key: tcell.KeyRune, rune: r, alt: true}, nil
+		}
+	}
+	if key, ok := searchKeyNames[lower]; ok && key != tcell.KeyRune {
+		return searchKey{key: key}, nil
+	}
+	return searchKey{}, fmt.Errorf("unknown key %q", name)
+}
+
+// eventKey returns the key of event as it is bound
+func eventKey(event *tcell.EventKey) searchKey {
+	if event.Key() != tcell.KeyRune {
+		return searchKey{key: event.Key()}
+	}
+	return searchKey{key: tcell.KeyRune, rune: event.Rune(), alt: event.Modifiers()&tcell.ModAlt != 0}
+}
+
+// label returns a short name of k for the help bar
+func (k searchKey) label() string {
+	if k.key == tcell.KeyRune {
+		name := string(k.rune)
+		if k.rune == ' ' {
+			name = "SPACE"
+		}
+		if k.alt {
+			return "M-" + name
+		}
+		return name
+	}
+	name := tcell.KeyNames[k.key]
+	if ctrl, ok := strings.CutPrefix(name, "Ctrl-"); ok {
+		return "^" + ctrl
+	}
+	return strings.ToUpper(name)
+}
+
+// searchKeymap maps keys to the actions of the search command
+type searchKeymap struct {
+	actions map[searchKey]searchAction
+	keys    map[searchAction][]searchKey
+}
+
+// newSearchKeymap returns the default key bindings with the actions in config
+// bound to the given keys instead. Unknown actions and keys, and keys bound
+// to more than one action, are errors.
+func newSearchKeymap(config map[string][]string) (*searchKeymap, error) {
+	bindings := make(map[searchAction][]string, len(searchDefaultKeys))
+	for action, keys := range searchDefaultKeys {
+		bindings[action] = keys
+	}
+	for name, keys := range config {
+		action := searchAction(name)
+		if _, ok := searchDefaultKeys[action]; !ok {
+			return nil, fmt.Errorf("unknown action %q in [search.keys] (expected %s)", name, strings.Join(searchActionNames(), ", "))
+		}
+		bindings[action] = keys
+	}
+
+	m := &searchKeymap{
+		actions: make(map[searchKey]searchAction),
+		keys:    make(map[searchAction][]searchKey),
+	}
+	// Actions are bound in a fixed order so that errors do not vary between runs
+	for _, name := range searchActionNames() {
+		action := searchAction(name)
+		for _, keyName := range bindings[action] {
+			key, err := parseSearchKey(keyName)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key for %s in [search.keys]: %w", action, err)
+			}
+			if other, ok := m.actions[key]; ok && other != action {
+				return nil, fmt.Errorf("key %q is bound to both %s and %s in [search.keys]", keyName, other, action)
+			}
+			m.actions[key] = action
+			m.keys[action] = append(m.keys[action], key)
+		}
+	}
+	return m, nil
+}
+
+// searchActionNames returns the names of all actions, sorted
+func searchActionNames() []string {
+	names := make([]string, 0, len(searchDefaultKeys))
+	for action := range searchDefaultKeys {
+		names = append(names, string(action))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// action returns the action bound to the key of event
+func (m *searchKeymap) action(event *tcell.EventKey) (searchAction, bool) {
+	action, ok := m.actions[eventKey(event)]
+	return action, ok
+}
+
+// help returns "KEY: description" for the help bar, using the first key of action,
+// or an empty string if no key is bound to it
+func (m *searchKeymap) help(action searchAction, description string) string {
+	keys := m.keys[action]
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0].label() + ": " + description
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestSearchKeymap(t *testing.T) {
+	keymap, err := newSearchKeymap(map[string][]string{
+		"up":   {"ctrl-p", "ctrl-k", "up"},
+		"down": {"ctrl-n", "ctrl-j", "down"},
+		// ctrl-s is free to bind once cycle-scope is bound to another key
+		"cycle-scope": {"alt-s"},
+		"delete":      {"ctrl-s"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create keymap: %v", err)
+	}
+
+	tests := []struct {
+		event    *tcell.EventKey
+		expected searchAction
+		bound    bool
+	}{
+		{tcell.NewEventKey(tcell.KeyCtrlP, 0, tcell.ModCtrl), actionUp, true},
+		{tcell.NewEventKey(tcell.KeyCtrlK, 0, tcell.ModCtrl), actionUp, true},
+		{tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), actionUp, true},
+		{tcell.NewEventKey(tcell.KeyCtrlJ, 0, tcell.ModCtrl), actionDown, true},
+		{tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModAlt), actionCycleScope, true},
+		{tcell.NewEventKey(tcell.KeyCtrlS, 0, tcell.ModCtrl), actionDelete, true},
+		{tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), actionAccept, true},
+		{tcell.NewEventKey(tcell.KeyCtrlD, 0, tcell.ModCtrl), "", false},
+		{tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModNone), "", false},
+		{tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone), actionMark, true},
+		{tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModAlt), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event.Name(), func(t *testing.T) {
+			action, ok := keymap.action(tt.event)
+			if ok != tt.bound || action != tt.expected {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.expected, tt.bound, action, ok)
+			}
+		})
+	}
+
+	if got := keymap.help(actionUp, "up"); got != "^P: up" {
+		t.Errorf("expected help %q, got %q", "^P: up", got)
+	}
+	if got := keymap.help(actionCycleScope, "scope"); got != "M-s: scope" {
+		t.Errorf("expected help %q, got %q", "M-s: scope", got)
+	}
+	if got := keymap.help(actionMark, "mark"); got != "SPACE: mark" {
+		t.Errorf("expected help %q, got %q", "SPACE: mark", got)
+	}
+
+	// Unbinding mark leaves space to be typed, and the help bar follows the bindings
+	keymap, err = newSearchKeymap(map[string][]string{"mark": {}, "toggle-mark": {"alt-space"}})
+	if err != nil {
+		t.Fatalf("failed to create keymap: %v", err)
+	}
+	if _, ok := keymap.action(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone)); ok {
+		t.Error("expected space not to be bound")
+	}
+	if action, _ := keymap.action(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModAlt)); action != actionToggleMark {
+		t.Errorf("expected alt-space to toggle marks, got %q", action)
+	}
+	if got := keymap.help(actionMark, "mark") + keymap.help(actionToggleMark, "mark"); got != "M-SPACE: mark" {
+		t.Errorf("expected help %q, got %q", "M-SPACE: mark", got)
+	}
+}
+
+func TestSearchKeymapErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     map[string][]string
+		expected string
+	}{
+		{"unknown action", map[string][]string{"jump": {"ctrl-j"}}, `unknown action "jump"`},
+		{"unknown key", map[string][]string{"up": {"ctrl-shift-p"}}, `unknown key "ctrl-shift-p"`},
+		{"plain rune", map[string][]string{"up": {"k"}}, `unknown key "k"`},
+		{"conflict", map[string][]string{"up": {"ctrl-r"}}, `key "ctrl-r" is bound to both toggle-regex and up`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSearchKeymap(tt.keys)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSearchTheme(t *testing.T) {
+	theme, err := newSearchTheme(config.SearchTheme{SelectionBackground: "Blue", Directory: "#00ff00", Match: "red"})
+	if err != nil {
+		t.Fatalf("failed to create theme: %v", err)
+	}
+	if _, bg, _ := theme.selection.Decompose(); bg != tcell.ColorBlue {
+		t.Errorf("expected selection background blue, got %v", bg)
+	}
+	if theme.directory != tcell.GetColor("#00ff00") {
+		t.Errorf("expected directory color #00ff00, got %v", theme.directory)
+	}
+
+	_, err = newSearchTheme(config.SearchTheme{Match: "reddish"})
+	if err == nil || !strings.Contains(err.Error(), `invalid match in [search.theme]: unknown color "reddish"`) {
+		t.Errorf("expected invalid color error, got %v", err)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		keywords []string
+		mode     history.CaseMode
+		expected string
+	}{
+		{"no keywords", "echo [red]", nil, history.CaseInsensitive, "echo [red[]"},
+		{"insensitive", "Git log | git grep", []string{"git"}, history.CaseInsensitive, "[red]Git[-] log | [red]git[-] grep"},
+		{"smart", "Git log | git grep", []string{"Git", "grep"}, history.CaseSmart, "[red]Git[-] log | git [red]grep[-]"},
+		{"escaped", "echo [x] [x]", []string{"[x]"}, history.CaseSensitive, "echo [red][x[][-] [red][x[][-]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlight(tt.text, keywordPattern(tt.keywords, tt.mode), "red")
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
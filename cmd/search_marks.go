@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+)
+
+// searchMarks are the entries marked in the search table for batch actions.
+// Marks are kept by entry ID, so they survive changing the query.
+type searchMarks struct {
+	entries map[string]history.Entry
+}
+
+func newSearchMarks() *searchMarks {
+	return &searchMarks{entries: make(map[string]history.Entry)}
+}
+
+// Toggle marks entry or removes its mark. Predicted entries have no ID and cannot be marked.
+func (m *searchMarks) Toggle(entry history.Entry) {
+	if entry.ID == "" {
+		return
+	}
+	if _, ok := m.entries[entry.ID]; ok {
+		delete(m.entries, entry.ID)
+	} else {
+		m.entries[entry.ID] = entry
+	}
+}
+
+// Has reports whether entry is marked
+func (m *searchMarks) Has(entry history.Entry) bool {
+	_, ok := m.entries[entry.ID]
+	return ok && entry.ID != ""
+}
+
+// Len returns the number of marked entries
+func (m *searchMarks) Len() int {
+	return len(m.entries)
+}
+
+// Clear removes all marks
+func (m *searchMarks) Clear() {
+	m.entries = make(map[string]history.Entry)
+}
+
+// Entries returns the marked entries in the order they were recorded, oldest first
+func (m *searchMarks) Entries() []history.Entry {
+	entries := make([]history.Entry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
+	}
+	// IDs are ULIDs, which sort in the order entries were recorded
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// joinCommands joins the commands of entries with sep
+func joinCommands(entries []history.Entry, sep string) string {
+	commands := make([]string, len(entries))
+	for i, entry := range entries {
+		commands[i] = entry.Command
+	}
+	return strings.Join(commands, sep)
+}
+
+// saveScript writes entries to a new executable script run by sh at path.
+// An existing file is not overwritten.
+func saveScript(path string, sh string, entries []history.Entry) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	return writeScript(f, sh, fmt.Sprintf("%d commands selected with duckhist search", len(entries)), entries)
+}
+
+// deleteEntries deletes the entries with the given IDs from the history database.
+// The search command reads the database read-only, so it is opened for writing here.
+func deleteEntries(cfg *config.Config, ids []string) (deleted int64, err error) {
+	manager, err := history.NewManagerReadWrite(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if closeErr := manager.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	deleted, err = manager.DeleteEntries(ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete commands: %w", err)
+	}
+	return deleted, nil
+}
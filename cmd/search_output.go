@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/shell"
+)
+
+// searchOutputFormats are the values of --output of the search command
+var searchOutputFormats = []string{"text", "widget", "json"}
+
+// searchSelection is what the search command outputs when a command is accepted
+type searchSelection struct {
+	Action searchAction `json:"action"`
+	// Text is the command line for the shell
+	Text    string               `json:"text"`
+	Entries []searchSelectedItem `json:"entries"`
+}
+
+// searchSelectedItem is an entry of a searchSelection
+type searchSelectedItem struct {
+	ID         string    `json:"id"`
+	Command    string    `json:"command"`
+	Directory  string    `json:"directory"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+// newSearchSelection returns the output of action on entries, with the
+// command line written for the shell dialect
+func newSearchSelection(action searchAction, entries []history.Entry, dialect shell.Dialect) *searchSelection {
+	s := &searchSelection{Action: action, Entries: make([]searchSelectedItem, len(entries))}
+	for i, entry := range entries {
+		s.Entries[i] = searchSelectedItem{
+			ID:         entry.ID,
+			Command:    entry.Command,
+			Directory:  entry.Directory,
+			ExecutedAt: entry.Timestamp,
+		}
+	}
+	switch {
+	case action == actionAcceptLines:
+		s.Text = joinCommands(entries, "\n")
+	case action == actionAcceptCd && len(entries) == 1:
+		s.Text = dialect.CdCommand(entries[0].Directory, entries[0].Command)
+	default:
+		s.Text = joinCommands(entries, " && ")
+	}
+	return s
+}
+
+// checkSearchOutput returns an error if format is not one of searchOutputFormats
+func checkSearchOutput(format string) error {
+	for _, f := range searchOutputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (expected text, widget or json)", format)
+}
+
+// writeSearchSelection writes s in format:
+// text is the command line, widget is the action on the first line followed by
+// the command line, and json is s as a JSON object on one line
+func writeSearchSelection(w io.Writer, format string, s *searchSelection) error {
+	switch format {
+	case "widget":
+		_, err := fmt.Fprintf(w, "%s\n%s\n", s.Action, s.Text)
+		return err
+	case "json":
+		encoder := json.NewEncoder(w)
+		// Shell widgets read the text as is, so && is not escaped for HTML
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(s)
+	default:
+		_, err := fmt.Fprintln(w, s.Text)
+		return err
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rivo/tview"
+)
+
+// searchPreviewTimeFormat is the format of the absolute time in the preview pane
+const searchPreviewTimeFormat = "2006-01-02 15:04:05 -0700"
+
+// formatPreview returns the text of the preview pane for entry, with tview
+// color tags. runs is the number of recordings of the command, or negative if
+// it is not known yet.
+func formatPreview(entry history.Entry, runs int) string {
+	var b strings.Builder
+	b.WriteString("[::b]Command[::-]\n")
+	b.WriteString(tview.Escape(entry.Command))
+	b.WriteString("\n\n")
+
+	field := func(name, value string) {
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(&b, "[::b]%-10s[::-] %s\n", name, tview.Escape(value))
+	}
+
+	// Predicted entries have no ID and were not recorded as such
+	if entry.ID == "" {
+		field("Predicted", "most likely next command")
+		field("Directory", entry.Directory)
+		return b.String()
+	}
+
+	field("Time", fmt.Sprintf("%s (%s)", entry.Timestamp.Local().Format(searchPreviewTimeFormat), humanize.Time(entry.Timestamp)))
+	field("Directory", entry.Directory)
+	field("Host", entry.Hostname)
+	field("User", entry.Username)
+	field("TTY", entry.TTY)
+	field("Session", entry.SID)
+	if runs >= 0 {
+		field("Runs", humanize.Comma(int64(runs)))
+	} else {
+		field("Runs", "…")
+	}
+	return b.String()
+}
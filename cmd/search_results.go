@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// searchPageSize is the number of entries fetched at once for the search table
+const searchPageSize = 200
+
+// searchHeaders are the column titles of the search table
+var searchHeaders = []string{"Date", "Directory", "Command"}
+
+// searchResults is the virtual content of the search table.
+// Row 0 is the header and the newest entry is shown in the last row, closest to
+// the input field. Entries are fetched in pages when they are first drawn, so
+// only the visible part of the history is loaded.
+type searchResults struct {
+	tview.TableContentReadOnly
+
+	ctx       context.Context
+	query     func() *history.HistoryQuery
+	predicted []history.Entry
+	total     int
+	// onLoad is called from a background goroutine after a page has been loaded
+	onLoad func()
+	// marks are highlighted if set
+	marks *searchMarks
+	// theme sets the colors of cells and matches of highlight are shown in the
+	// match color of theme, if both are set
+	theme     *searchTheme
+	highlight *regexp.Regexp
+
+	mu      sync.Mutex
+	pages   map[int][]history.Entry
+	loading map[int]bool
+	err     error
+}
+
+// newSearchResults counts the entries matching query and returns results that
+// load them on demand. Predicted entries are shown before the matching entries.
+// Page loads stop when ctx is canceled.
+func newSearchResults(ctx context.Context, query func() *history.HistoryQuery, predicted []history.Entry, onLoad func()) (*searchResults, error) {
+	total, err := query().CountContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &searchResults{
+		ctx:       ctx,
+		query:     query,
+		predicted: predicted,
+		total:     total,
+		onLoad:    onLoad,
+		pages:     make(map[int][]history.Entry),
+		loading:   make(map[int]bool),
+	}, nil
+}
+
+// Len returns the number of entries including predicted ones
+func (r *searchResults) Len() int {
+	return len(r.predicted) + r.total
+}
+
+// Err returns the error of the last failed page load
+func (r *searchResults) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Entry returns the i-th entry, newest first. If its page has not been loaded
+// yet, the load is started in the background and ok is false.
+func (r *searchResults) Entry(i int) (history.Entry, bool) {
+	if i < 0 || i >= r.Len() {
+		return history.Entry{}, false
+	}
+	if i < len(r.predicted) {
+		return r.predicted[i], true
+	}
+	i -= len(r.predicted)
+	page := i / searchPageSize
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entries, ok := r.pages[page]; ok {
+		if i%searchPageSize < len(entries) {
+			return entries[i%searchPageSize], true
+		}
+		return history.Entry{}, false
+	}
+	if !r.loading[page] {
+		r.loading[page] = true
+		go r.load(page)
+	}
+	return history.Entry{}, false
+}
+
+// load fetches one page of entries
+func (r *searchResults) load(page int) {
+	entries, err := r.query().Offset(page * searchPageSize).Limit(searchPageSize).GetEntriesContext(r.ctx)
+	if r.ctx.Err() != nil {
+		return
+	}
+
+	r.mu.Lock()
+	// A failed page is stored empty so that it is not retried on every draw
+	r.pages[page] = entries
+	delete(r.loading, page)
+	if err != nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+
+	if r.onLoad != nil {
+		r.onLoad()
+	}
+}
+
+// EntryAtRow returns the entry shown in the given table row
+func (r *searchResults) EntryAtRow(row int) (history.Entry, bool) {
+	if row < 1 {
+		return history.Entry{}, false
+	}
+	return r.Entry(r.Len() - row)
+}
+
+// GetCell implements tview.TableContent
+func (r *searchResults) GetCell(row, column int) *tview.TableCell {
+	if column < 0 || column >= len(searchHeaders) {
+		return nil
+	}
+	if row == 0 {
+		return tview.NewTableCell(searchHeaders[column]).SetSelectable(false)
+	}
+
+	entry, ok := r.EntryAtRow(row)
+	if !ok {
+		return tview.NewTableCell("…")
+	}
+	marked := r.marks != nil && r.marks.Has(entry)
+	var cell *tview.TableCell
+	switch column {
+	case 0:
+		// Format date as relative time; predicted entries have no ID
+		if entry.ID == "" {
+			cell = tview.NewTableCell("predicted")
+		} else {
+			cell = tview.NewTableCell(humanize.Time(entry.Timestamp))
+		}
+	case 1:
+		cell = tview.NewTableCell(tview.Escape(ShortenPath(entry.Directory, 20)))
+		if r.theme != nil && r.theme.directory != tcell.ColorDefault {
+			cell.SetTextColor(r.theme.directory)
+		}
+	default:
+		text := tview.Escape(entry.Command)
+		if r.theme != nil {
+			text = highlight(entry.Command, r.highlight, r.theme.match)
+		}
+		if marked {
+			text = "* " + text
+		}
+		cell = tview.NewTableCell(text)
+	}
+	if marked {
+		color := tcell.ColorYellow
+		if r.theme != nil && r.theme.marked != tcell.ColorDefault {
+			color = r.theme.marked
+		}
+		cell.SetTextColor(color)
+	}
+	return cell
+}
+
+// GetRowCount implements tview.TableContent
+func (r *searchResults) GetRowCount() int {
+	return r.Len() + 1
+}
+
+// GetColumnCount implements tview.TableContent
+func (r *searchResults) GetColumnCount() int {
+	return len(searchHeaders)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+// searchScope restricts the entries shown by the search command
+type searchScope struct {
+	name string
+	// detail describes the restriction, e.g. the directory, for the help bar
+	detail string
+	apply  func(q *history.HistoryQuery) *history.HistoryQuery
+}
+
+// searchScopeNames are the names of all scopes in the order they are cycled through
+var searchScopeNames = []string{"all", "directory", "tree", "session", "host", "repo"}
+
+// searchScopes returns the scopes available when searching from dir, in the
+// order they are cycled through. Scopes needing information that is not
+// available, such as the git repository outside of one, are left out.
+func searchScopes(dir string) []searchScope {
+	scopes := []searchScope{
+		{name: "all", apply: func(q *history.HistoryQuery) *history.HistoryQuery { return q }},
+		{name: "directory", detail: ShortenPath(dir, 30), apply: func(q *history.HistoryQuery) *history.HistoryQuery {
+			return q.InDirectory(dir)
+		}},
+		{name: "tree", detail: ShortenPath(dir, 30), apply: func(q *history.HistoryQuery) *history.HistoryQuery {
+			return q.UnderDirectory(dir)
+		}},
+	}
+	if sid, err := currentSessionID(); err == nil {
+		scopes = append(scopes, searchScope{name: "session", apply: func(q *history.HistoryQuery) *history.HistoryQuery {
+			return q.InSession(sid)
+		}})
+	}
+	if host, err := os.Hostname(); err == nil {
+		scopes = append(scopes, searchScope{name: "host", detail: host, apply: func(q *history.HistoryQuery) *history.HistoryQuery {
+			return q.OnHost(host)
+		}})
+	}
+	if root, ok := findGitRoot(dir); ok {
+		scopes = append(scopes, searchScope{name: "repo", detail: filepath.Base(root), apply: func(q *history.HistoryQuery) *history.HistoryQuery {
+			return q.UnderDirectory(root)
+		}})
+	}
+	return scopes
+}
+
+// query returns a function building the queries of one search in the scope,
+// with the entries executed in currentDir first
+func (s searchScope) query(manager *history.Manager, match func(q *history.HistoryQuery) *history.HistoryQuery, currentDir string) func() *history.HistoryQuery {
+	return func() *history.HistoryQuery {
+		return match(s.apply(manager.Query())).OrderByCurrentDirFirst(currentDir)
+	}
+}
+
+// findSearchScope returns the index of the scope with the given name
+func findSearchScope(scopes []searchScope, name string) (int, error) {
+	for i, scope := range scopes {
+		if scope.name == name {
+			return i, nil
+		}
+	}
+	switch name {
+	case "session":
+		return 0, fmt.Errorf("scope %q is not available: %s is not set", name, sessionEnv)
+	case "repo":
+		return 0, fmt.Errorf("scope %q is not available: not in a git repository", name)
+	}
+	for _, n := range searchScopeNames {
+		if n == name {
+			return 0, fmt.Errorf("scope %q is not available", name)
+		}
+	}
+	return 0, fmt.Errorf("unknown scope %q (expected %s)", name, strings.Join(searchScopeNames, ", "))
+}
+
+// findGitRoot returns the root of the git working tree containing dir.
+// A .git file instead of a directory marks a linked worktree or a submodule.
+func findGitRoot(dir string) (string, bool) {
+	dir = filepath.Clean(dir)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
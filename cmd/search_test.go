@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/sett4/duckhist/internal/config"
 	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/shell"
 )
 
 func TestSearchCommand(t *testing.T) {
@@ -126,6 +131,84 @@ current_directory_history_limit = 5
 	}
 }
 
+func TestSearchResults(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+	total := searchPageSize + 50
+	records := make([]history.CommandRecord, total)
+	for i := range records {
+		records[i] = history.CommandRecord{
+			Command:    fmt.Sprintf("echo %d", i),
+			Directory:  "/tmp",
+			Hostname:   "localhost",
+			Username:   "testuser",
+			ExecutedAt: time.Now(),
+		}
+	}
+	if _, err := manager.AddCommands(records); err != nil {
+		t.Fatalf("failed to add commands: %v", err)
+	}
+
+	query := func() *history.HistoryQuery {
+		return manager.Query().Search("echo").OrderByCurrentDirFirst("/home")
+	}
+	predicted := []history.Entry{{Command: "make"}}
+	loaded := make(chan struct{}, 10)
+	results, err := newSearchResults(context.Background(), query, predicted, func() { loaded <- struct{}{} })
+	if err != nil {
+		t.Fatalf("newSearchResults failed: %v", err)
+	}
+
+	if results.Len() != total+1 || results.GetRowCount() != total+2 {
+		t.Errorf("unexpected size: %d entries, %d rows", results.Len(), results.GetRowCount())
+	}
+	if entry, ok := results.EntryAtRow(results.GetRowCount() - 1); !ok || entry.Command != "make" {
+		t.Errorf("expected prediction in the last row, got %+v", entry)
+	}
+	if cell := results.GetCell(0, 2); cell.Text != "Command" {
+		t.Errorf("unexpected header: %q", cell.Text)
+	}
+
+	// Entries are loaded in the background the first time they are requested
+	for _, i := range []int{1, 1 + searchPageSize} {
+		if _, ok := results.Entry(i); ok {
+			t.Fatalf("expected entry %d not to be loaded yet", i)
+		}
+		select {
+		case <-loaded:
+		case <-time.After(5 * time.Second):
+			t.Fatal("page was not loaded")
+		}
+	}
+	if entry, ok := results.Entry(1); !ok || entry.Command != fmt.Sprintf("echo %d", total-1) {
+		t.Errorf("expected newest entry, got %+v", entry)
+	}
+	if entry, ok := results.EntryAtRow(1); !ok || entry.Command != "echo 0" {
+		t.Errorf("expected oldest entry in the first row, got %+v", entry)
+	}
+	if err := results.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := newSearchResults(ctx, query, nil, nil); err == nil {
+		t.Error("expected error for canceled search")
+	}
+}
+
 func TestShortenPath(t *testing.T) {
 	// Test cases
 	thuruTests := []struct {
@@ -150,3 +233,279 @@ func TestShortenPath(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatPreview(t *testing.T) {
+	executedAt := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	entry := history.Entry{
+		ID:        "01HX",
+		Command:   "echo [red]\nsecond line",
+		Timestamp: executedAt,
+		Hostname:  "localhost",
+		Directory: "/home/user/project",
+		Username:  "user",
+		SID:       "s1",
+	}
+
+	text := formatPreview(entry, 1234)
+	for _, expected := range []string{
+		"echo [red[]\nsecond line\n",
+		executedAt.Local().Format(searchPreviewTimeFormat),
+		"/home/user/project",
+		"localhost",
+		"[::b]TTY       [::-] -\n",
+		"[::b]Session   [::-] s1\n",
+		"1,234",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected preview to contain %q, got:\n%s", expected, text)
+		}
+	}
+
+	if text := formatPreview(entry, -1); !strings.Contains(text, "[::b]Runs      [::-] …") {
+		t.Errorf("expected unknown run count, got:\n%s", text)
+	}
+	if text := formatPreview(history.Entry{Command: "make", Directory: "/src"}, -1); !strings.Contains(text, "Predicted") || strings.Contains(text, "Runs") {
+		t.Errorf("unexpected preview of a prediction:\n%s", text)
+	}
+}
+
+func TestSearchScopes(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := filepath.Join(tmpDir, "repo")
+	sub := filepath.Join(repo, "sub")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("failed to get hostname: %v", err)
+	}
+	for i, r := range []struct{ dir, sid, host string }{
+		{sub, "s1", host},
+		{filepath.Join(sub, "deeper"), "s2", host},
+		{repo, "s2", "other"},
+		{tmpDir, "s1", host},
+	} {
+		if _, err := manager.AddCommand(fmt.Sprintf("echo %d", i), r.dir, "", r.sid, r.host, "testuser", time.Now(), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+
+	t.Setenv(sessionEnv, "s1")
+	scopes := searchScopes(sub)
+	expected := map[string]int{"all": 4, "directory": 1, "tree": 2, "session": 2, "host": 3, "repo": 3}
+	if len(scopes) != len(expected) {
+		t.Fatalf("expected %d scopes, got %d", len(expected), len(scopes))
+	}
+	for i, name := range searchScopeNames {
+		if scopes[i].name != name {
+			t.Errorf("expected scope %d to be %s, got %s", i, name, scopes[i].name)
+		}
+		count, err := scopes[i].apply(manager.Query()).Count()
+		if err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		if count != expected[name] {
+			t.Errorf("scope %s: expected %d entries, got %d", name, expected[name], count)
+		}
+	}
+
+	// Outside of a repository and without a session, those scopes are left out
+	t.Setenv(sessionEnv, "")
+	scopes = searchScopes(tmpDir)
+	if _, err := findSearchScope(scopes, "repo"); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("expected repo scope not to be available, got %v", err)
+	}
+	if _, err := findSearchScope(scopes, "session"); err == nil {
+		t.Error("expected session scope not to be available")
+	}
+	if _, err := findSearchScope(scopes, "galaxy"); err == nil || !strings.Contains(err.Error(), "unknown scope") {
+		t.Errorf("expected unknown scope error, got %v", err)
+	}
+	if i, err := findSearchScope(scopes, "host"); err != nil || scopes[i].name != "host" {
+		t.Errorf("expected host scope, got %d, %v", i, err)
+	}
+}
+
+func TestSearchScopeCycledWhileLoading(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+	// Only the newest entry was executed in the current directory
+	total := 2 * searchPageSize
+	records := make([]history.CommandRecord, total)
+	for i := range records {
+		records[i] = history.CommandRecord{Command: fmt.Sprintf("echo %d", i), Directory: "/tmp", ExecutedAt: time.Now()}
+	}
+	records[total-1].Directory = "/home"
+	if _, err := manager.AddCommands(records); err != nil {
+		t.Fatalf("failed to add commands: %v", err)
+	}
+
+	scopes := searchScopes("/home")
+	scopeIndex := 0
+	match := func(q *history.HistoryQuery) *history.HistoryQuery { return q }
+	loaded := make(chan struct{}, 10)
+	results, err := newSearchResults(context.Background(), scopes[scopeIndex].query(manager, match, "/home"), nil, func() { loaded <- struct{}{} })
+	if err != nil {
+		t.Fatalf("newSearchResults failed: %v", err)
+	}
+
+	// Cycle to the directory scope while the pages of the previous search are loading
+	for _, i := range []int{0, searchPageSize} {
+		results.Entry(i)
+		scopeIndex = (scopeIndex + 1) % len(scopes)
+		select {
+		case <-loaded:
+		case <-time.After(5 * time.Second):
+			t.Fatal("page was not loaded")
+		}
+	}
+	if scopes[scopeIndex].name != "tree" {
+		t.Fatalf("expected tree scope, got %s", scopes[scopeIndex].name)
+	}
+	if results.total != total {
+		t.Errorf("expected %d entries, got %d", total, results.total)
+	}
+	if entry, ok := results.Entry(searchPageSize); !ok || entry.Command != fmt.Sprintf("echo %d", searchPageSize-1) {
+		t.Errorf("expected the second page of all history, got %+v, %v", entry, ok)
+	}
+	if err := results.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSearchMarks(t *testing.T) {
+	marks := newSearchMarks()
+	older := history.Entry{ID: "01A", Command: "cd build", Directory: "/src"}
+	newer := history.Entry{ID: "01B", Command: "make", Directory: "/src/build"}
+	marks.Toggle(newer)
+	marks.Toggle(older)
+	marks.Toggle(history.Entry{Command: "predicted"})
+	if marks.Len() != 2 || !marks.Has(older) || marks.Has(history.Entry{Command: "predicted"}) {
+		t.Fatalf("unexpected marks: %+v", marks.Entries())
+	}
+
+	// Marked commands are joined in the order they were recorded
+	if got := joinCommands(marks.Entries(), " && "); got != "cd build && make" {
+		t.Errorf("unexpected joined commands: %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "setup.sh")
+	if err := saveScript(path, "zsh", marks.Entries()); err != nil {
+		t.Fatalf("saveScript failed: %v", err)
+	}
+	script, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read script: %v", err)
+	}
+	if !strings.HasPrefix(string(script), "#!/usr/bin/env zsh\n# 2 commands selected") || !strings.HasSuffix(string(script), "\ncd /src\ncd build\n\ncd /src/build\nmake\n") {
+		t.Errorf("unexpected script:\n%s", script)
+	}
+	if err := saveScript(path, "zsh", marks.Entries()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected existing script not to be overwritten, got %v", err)
+	}
+
+	marks.Toggle(older)
+	if marks.Has(older) || marks.Len() != 1 {
+		t.Errorf("expected mark to be removed, got %+v", marks.Entries())
+	}
+	marks.Clear()
+	if marks.Len() != 0 {
+		t.Errorf("expected no marks after Clear, got %d", marks.Len())
+	}
+}
+
+func TestSearchSelection(t *testing.T) {
+	executedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []history.Entry{
+		{ID: "01A", Command: "make", Directory: "/src/my app", Timestamp: executedAt},
+		{ID: "01B", Command: "make test", Directory: "/src/it's", Timestamp: executedAt},
+	}
+
+	tests := []struct {
+		name     string
+		action   searchAction
+		entries  []history.Entry
+		dialect  shell.Dialect
+		format   string
+		expected string
+	}{
+		{"accept", actionAccept, entries, shell.POSIX, "text", "make && make test\n"},
+		{"lines", actionAcceptLines, entries, shell.POSIX, "text", "make\nmake test\n"},
+		{"cd", actionAcceptCd, entries[1:], shell.POSIX, "text", "cd '/src/it'\\''s' && make test\n"},
+		{"cd fish", actionAcceptCd, entries[1:], shell.Fish, "text", "cd '/src/it\\'s' && make test\n"},
+		{"widget", actionEdit, entries[:1], shell.POSIX, "widget", "edit\nmake\n"},
+		{"json", actionAcceptCd, entries[:1], shell.POSIX, "json",
+			`{"action":"accept-cd","text":"cd '/src/my app' && make","entries":[{"id":"01A","command":"make","directory":"/src/my app","executed_at":"2024-01-02T03:04:05Z"}]}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			if err := writeSearchSelection(&buf, tt.format, newSearchSelection(tt.action, tt.entries, tt.dialect)); err != nil {
+				t.Fatalf("failed to write selection: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	if err := checkSearchOutput("yaml"); err == nil {
+		t.Error("expected an error for an unknown output format")
+	}
+}
+
+func TestSearchHeight(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   int
+		flag     int
+		expected int
+		wantErr  bool
+	}{
+		{"fullscreen", 0, 0, 0, false},
+		{"config", 10, 0, 10, false},
+		{"flag overrides config", 10, 20, 20, false},
+		{"too small", 0, 3, 0, true},
+		{"negative", -1, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Search: config.SearchConfig{Height: tt.config}}
+			got, err := searchHeight(cfg, tt.flag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// searchTheme holds the colors of the search command
+type searchTheme struct {
+	selection tcell.Style
+	directory tcell.Color
+	// match and marked are color tags of tview
+	match  string
+	marked tcell.Color
+}
+
+// newSearchTheme validates the colors of the [search.theme] section of the config file.
+// Empty colors keep the defaults of tview.
+func newSearchTheme(cfg config.SearchTheme) (*searchTheme, error) {
+	colors := []struct {
+		option string
+		name   string
+	}{
+		{option: "selection_fg", name: cfg.SelectionForeground},
+		{option: "selection_bg", name: cfg.SelectionBackground},
+		{option: "directory", name: cfg.Directory},
+		{option: "match", name: cfg.Match},
+		{option: "marked", name: cfg.Marked},
+	}
+	parsed := make([]tcell.Color, len(colors))
+	for i, c := range colors {
+		color, err := parseColor(c.name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in [search.theme]: %w", c.option, err)
+		}
+		parsed[i] = color
+	}
+
+	theme := &searchTheme{
+		// tview inverts the colors of the selected row by default
+		selection: tcell.StyleDefault.
+			Foreground(tview.Styles.PrimitiveBackgroundColor).
+			Background(tview.Styles.PrimaryTextColor),
+		directory: parsed[2],
+		marked:    parsed[4],
+	}
+	if parsed[0] != tcell.ColorDefault {
+		theme.selection = theme.selection.Foreground(parsed[0])
+	}
+	if parsed[1] != tcell.ColorDefault {
+		theme.selection = theme.selection.Background(parsed[1])
+	}
+	if parsed[3] != tcell.ColorDefault {
+		theme.match = strings.ToLower(cfg.Match)
+	}
+	return theme, nil
+}
+
+// parseColor parses a color name such as "yellow" or "#ffcc00".
+// An empty name and "default" are tcell.ColorDefault.
+func parseColor(name string) (tcell.Color, error) {
+	if name == "" || strings.EqualFold(name, "default") {
+		return tcell.ColorDefault, nil
+	}
+	color := tcell.GetColor(strings.ToLower(name))
+	if color == tcell.ColorDefault {
+		return color, fmt.Errorf("unknown color %q", name)
+	}
+	return color, nil
+}
+
+// highlight returns text with tview color tags marking the matches of re in
+// color. The rest of the text is escaped so that it is not read as tags.
+func highlight(text string, re *regexp.Regexp, color string) string {
+	if re == nil || color == "" {
+		return tview.Escape(text)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringIndex(text, -1) {
+		if match[0] == match[1] {
+			continue // Empty matches are not visible
+		}
+		b.WriteString(tview.Escape(text[last:match[0]]))
+		b.WriteString("[" + color + "]")
+		b.WriteString(tview.Escape(text[match[0]:match[1]]))
+		b.WriteString("[-]")
+		last = match[1]
+	}
+	b.WriteString(tview.Escape(text[last:]))
+	return b.String()
+}
+
+// keywordPattern returns a pattern matching any of keywords as the case mode
+// matches them, for highlighting, or nil if there are no keywords
+func keywordPattern(keywords []string, mode history.CaseMode) *regexp.Regexp {
+	if len(keywords) == 0 {
+		return nil
+	}
+	parts := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		parts[i] = regexp.QuoteMeta(keyword)
+		if !mode.Sensitive(keyword) {
+			parts[i] = "(?i:" + parts[i] + ")"
+		}
+	}
+	return regexp.MustCompile(strings.Join(parts, "|"))
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/shell"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionCmd groups the subcommands operating on a single shell session
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Inspect a single shell session",
+	Long:  `Inspect a single shell session. Use the sessions command to list session IDs.`,
+}
+
+// sessionShowCmd represents the session show command
+var sessionShowCmd = &cobra.Command{
+	Use:   "show [sid]",
+	Short: "Show the timeline of a shell session",
+	Long: `Print the commands of one shell session in the order they were run, with
+their timestamps and the directory changes between them.
+Without an argument, the current session ($DUCKHIST_SID) is shown.
+With --script, the session is exported as a shell script that changes into
+the recorded directories and runs the commands again.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSessionShow,
+}
+
+var (
+	sessionShowScript bool
+	sessionShowShell  string
+)
+
+func init() {
+	sessionShowCmd.Flags().BoolVar(&sessionShowScript, "script", false, "export the session as a runnable shell script")
+	sessionShowCmd.Flags().StringVar(&sessionShowShell, "shell", "", "shell the script is run by: sh, bash, zsh, ksh or dash (default zsh)")
+	sessionCmd.AddCommand(sessionShowCmd)
+	rootCmd.AddCommand(sessionCmd)
+}
+
+func runSessionShow(cmd *cobra.Command, args []string) error {
+	var sid string
+	if len(args) > 0 {
+		sid = args[0]
+	} else {
+		var err error
+		sid, err = currentSessionID()
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	entries, err := manager.Query().InSession(sid).OrderByOldestFirst().GetEntries()
+	if err != nil {
+		return fmt.Errorf("failed to get session history: %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("session not found: %s", sid)
+	}
+
+	if sessionShowScript {
+		sh, err := scriptShell(sessionShowShell)
+		if err != nil {
+			return err
+		}
+		return writeSessionScript(cmd.OutOrStdout(), sh, sid, entries)
+	}
+	return writeSessionTimeline(cmd.OutOrStdout(), sid, entries)
+}
+
+// writeSessionTimeline prints the entries of a session, oldest first, with a line
+// for every change of directory
+func writeSessionTimeline(w io.Writer, sid string, entries []history.Entry) error {
+	first, last := entries[0], entries[len(entries)-1]
+	if _, err := fmt.Fprintf(w, "Session %s on %s, %d commands from %s to %s\n",
+		sid, first.Hostname, len(entries),
+		first.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		last.Timestamp.Local().Format("2006-01-02 15:04:05")); err != nil {
+		return err
+	}
+
+	dir := ""
+	for _, entry := range entries {
+		if entry.Directory != dir {
+			dir = entry.Directory
+			if _, err := fmt.Fprintf(w, "\n  in %s\n", dir); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s  %s\n", entry.Timestamp.Local().Format("15:04:05"), entry.Command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSessionScript exports the entries of a session as a script run by sh
+func writeSessionScript(w io.Writer, sh string, sid string, entries []history.Entry) error {
+	first, last := entries[0], entries[len(entries)-1]
+	return writeScript(w, sh, fmt.Sprintf("Session %s recorded on %s from %s to %s",
+		sid, first.Hostname,
+		first.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		last.Timestamp.Local().Format("2006-01-02 15:04:05")), entries)
+}
+
+// scriptShell returns the shell scripts are exported for. Commands are recorded
+// by the zsh integration and may use zsh syntax, so they are replayed by zsh
+// unless another POSIX shell is named.
+func scriptShell(name string) (string, error) {
+	if name == "" {
+		return "zsh", nil
+	}
+	dialect, err := shell.ParseDialect(name)
+	if err != nil {
+		return "", err
+	}
+	if dialect != shell.POSIX {
+		return "", fmt.Errorf("scripts cannot be exported for %s", name)
+	}
+	return filepath.Base(name), nil
+}
+
+// writeScript exports entries as a script run by sh with a comment describing them.
+// Directories are changed with absolute paths before the commands that were run there,
+// so recorded cd commands with relative paths do not break the replay.
+func writeScript(w io.Writer, sh string, description string, entries []history.Entry) error {
+	if _, err := fmt.Fprintf(w, "#!/usr/bin/env %s\n# %s\n# Review the commands before running this script.\nset -e\n", sh, description); err != nil {
+		return err
+	}
+
+	dir := ""
+	for _, entry := range entries {
+		if entry.Directory != dir {
+			dir = entry.Directory
+			if _, err := fmt.Fprintf(w, "\ncd %s\n", shell.Quote(dir)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, entry.Command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestSessionShow(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q", dbPath)), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	testCommands := []struct {
+		command   string
+		directory string
+		sid       string
+	}{
+		{"cd 'my project'", "/home/user", "s1"},
+		{"echo other", "/tmp", "s2"},
+		{"make", "/home/user/my project", "s1"},
+		{"make test", "/home/user/my project", "s1"},
+		{"cd ..", "/home/user/my project", "s1"},
+		{"ls", "/home/user", "s1"},
+	}
+	for i, tc := range testCommands {
+		executedAt := start.Add(time.Duration(i) * time.Minute)
+		if _, err := manager.AddCommand(tc.command, tc.directory, "", tc.sid, "localhost", "testuser", executedAt, true); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	cfgFile = configPath
+	defer func() { sessionShowScript = false }()
+
+	t.Run("timeline", func(t *testing.T) {
+		sessionShowScript = false
+		var buf bytes.Buffer
+		sessionShowCmd.SetOut(&buf)
+		if err := runSessionShow(sessionShowCmd, []string{"s1"}); err != nil {
+			t.Fatalf("runSessionShow failed: %v", err)
+		}
+		expected := `Session s1 on localhost, 5 commands from 2024-05-01 09:00:00 to 2024-05-01 09:05:00
+
+  in /home/user
+09:00:00  cd 'my project'
+
+  in /home/user/my project
+09:02:00  make
+09:03:00  make test
+09:04:00  cd ..
+
+  in /home/user
+09:05:00  ls
+`
+		if buf.String() != expected {
+			t.Errorf("unexpected timeline:\n%s", buf.String())
+		}
+	})
+
+	t.Run("script", func(t *testing.T) {
+		sessionShowScript = true
+		t.Setenv(sessionEnv, "s1")
+		var buf bytes.Buffer
+		sessionShowCmd.SetOut(&buf)
+		if err := runSessionShow(sessionShowCmd, nil); err != nil {
+			t.Fatalf("runSessionShow failed: %v", err)
+		}
+		expected := `#!/usr/bin/env zsh
+# Session s1 recorded on localhost from 2024-05-01 09:00:00 to 2024-05-01 09:05:00
+# Review the commands before running this script.
+set -e
+
+cd /home/user
+cd 'my project'
+
+cd '/home/user/my project'
+make
+make test
+cd ..
+
+cd /home/user
+ls
+`
+		if buf.String() != expected {
+			t.Errorf("unexpected script:\n%s", buf.String())
+		}
+
+		sessionShowShell = "/bin/bash"
+		defer func() { sessionShowShell = "" }()
+		buf.Reset()
+		if err := runSessionShow(sessionShowCmd, nil); err != nil {
+			t.Fatalf("runSessionShow failed: %v", err)
+		}
+		if !strings.HasPrefix(buf.String(), "#!/usr/bin/env bash\n") {
+			t.Errorf("expected bash script, got:\n%s", buf.String())
+		}
+		sessionShowShell = "fish"
+		if err := runSessionShow(sessionShowCmd, nil); err == nil || !strings.Contains(err.Error(), "cannot be exported for fish") {
+			t.Errorf("expected error for fish, got %v", err)
+		}
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		if err := runSessionShow(sessionShowCmd, []string{"unknown"}); err == nil {
+			t.Error("expected error for unknown session")
+		}
+	})
+}
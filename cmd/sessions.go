@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionEnv is the environment variable holding the session ID of the current shell.
+// It is set by the zsh integration script.
+const sessionEnv = "DUCKHIST_SID"
+
+// sessionsCmd represents the sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List shell sessions",
+	Long: `List the most recent shell sessions, newest first, with the time of their
+first and last command, the directory of the first command and the number of
+recorded commands. The current session is marked with an asterisk.`,
+	RunE: runSessions,
+}
+
+var (
+	sessionsLimit int
+)
+
+func init() {
+	sessionsCmd.Flags().IntVarP(&sessionsLimit, "limit", "n", 20, "maximum number of sessions to list")
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+func runSessions(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	sessions, err := manager.ListSessions(sessionsLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	current := os.Getenv(sessionEnv)
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  SID\tSTART\tEND\tCOMMANDS\tHOST\tDIRECTORY")
+	for _, s := range sessions {
+		marker := " "
+		if s.SID == current {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%d\t%s\t%s\n",
+			marker, s.SID,
+			s.Start.Local().Format("2006-01-02 15:04:05"),
+			s.End.Local().Format("2006-01-02 15:04:05"),
+			s.Count, s.Hostname, s.Directory)
+	}
+	return w.Flush()
+}
+
+// currentSessionID returns the session ID of the shell duckhist was started from
+func currentSessionID() (string, error) {
+	sid := os.Getenv(sessionEnv)
+	if sid == "" {
+		return "", fmt.Errorf("%s is not set; source the zsh integration script to record sessions", sessionEnv)
+	}
+	return sid, nil
+}
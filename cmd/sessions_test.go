@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q", dbPath)), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	testCommands := []struct {
+		command   string
+		directory string
+		tty       string
+		sid       string
+	}{
+		{"cd /project", "/home", "/dev/pts/1", "s1"},
+		{"make", "/project", "/dev/pts/1", "s1"},
+		{"top", "/home", "/dev/pts/2", "s2"},
+		{"make test", "/project", "/dev/pts/1", "s1"},
+		{"imported", "/home", "", ""},
+	}
+	for i, tc := range testCommands {
+		executedAt := start.Add(time.Duration(i) * time.Minute)
+		if _, err := manager.AddCommand(tc.command, tc.directory, tc.tty, tc.sid, "localhost", "testuser", executedAt, true); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	// Entries recorded before tty and sid existed have NULL values
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO history (id, command, executed_at, executing_host, executing_dir, executing_user) VALUES ('00000000000000000000000000', 'old', ?, 'localhost', '/home', 'testuser')", start); err != nil {
+		t.Fatalf("failed to insert legacy entry: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	manager, err = history.NewManagerReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	t.Run("list sessions", func(t *testing.T) {
+		sessions, err := manager.ListSessions(10)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if len(sessions) != 2 {
+			t.Fatalf("expected 2 sessions, got %+v", sessions)
+		}
+		s1 := sessions[0]
+		if s1.SID != "s1" || s1.Count != 3 || s1.Directory != "/home" || s1.Hostname != "localhost" {
+			t.Errorf("unexpected session: %+v", s1)
+		}
+		if !s1.Start.Equal(start) || !s1.End.Equal(start.Add(3*time.Minute)) {
+			t.Errorf("unexpected session times: %v - %v", s1.Start, s1.End)
+		}
+		if sessions[1].SID != "s2" || sessions[1].Count != 1 {
+			t.Errorf("unexpected session: %+v", sessions[1])
+		}
+
+		limited, err := manager.ListSessions(1)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if len(limited) != 1 {
+			t.Errorf("expected 1 session, got %d", len(limited))
+		}
+	})
+
+	t.Run("filter by session and tty", func(t *testing.T) {
+		entries, err := manager.Query().InSession("s1").GetEntries()
+		if err != nil {
+			t.Fatalf("GetEntries failed: %v", err)
+		}
+		if len(entries) != 3 || entries[0].Command != "make test" {
+			t.Errorf("unexpected session entries: %+v", entries)
+		}
+
+		entries, err = manager.Query().OnTTY("/dev/pts/2").GetEntries()
+		if err != nil {
+			t.Fatalf("GetEntries failed: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Command != "top" {
+			t.Errorf("unexpected tty entries: %+v", entries)
+		}
+	})
+
+	t.Run("sessions command", func(t *testing.T) {
+		cfgFile = configPath
+		t.Setenv(sessionEnv, "s2")
+
+		var buf bytes.Buffer
+		sessionsCmd.SetOut(&buf)
+		if err := runSessions(sessionsCmd, nil); err != nil {
+			t.Fatalf("runSessions failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected header and 2 sessions, got %q", buf.String())
+		}
+		if !strings.HasPrefix(lines[1], "  s1 ") || !strings.HasPrefix(lines[2], "* s2 ") {
+			t.Errorf("unexpected output: %q", buf.String())
+		}
+	})
+
+	t.Run("current session", func(t *testing.T) {
+		t.Setenv(sessionEnv, "")
+		if _, err := currentSessionID(); err == nil {
+			t.Error("expected error when the session ID is not set")
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// sqlCmd represents the sql command
+var sqlCmd = &cobra.Command{
+	Use:   "sql <query>",
+	Short: "Run a read-only SQL query against the history database",
+	Long: `Run a read-only SQL query against the history database and print the result
+as a table, CSV, JSON or newline-delimited JSON. The query cannot modify the database.
+
+Commands are stored in the history table with the columns:
+  id              ULID of the entry, sorting in the order entries were recorded
+  command         the command line
+  executed_at     time the command was run
+  executing_host  hostname
+  executing_dir   working directory
+  executing_user  username
+  tty             terminal (may be NULL for old entries)
+  sid             shell session ID (may be NULL for old entries)
+`,
+	Example: `  duckhist sql "SELECT command, runs FROM commands ORDER BY runs DESC LIMIT 10"
+  duckhist sql -f csv "SELECT * FROM commands_by_dir WHERE directory = '/src/app'"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSQL,
+}
+
+var (
+	sqlFormat string
+)
+
+func init() {
+	var views strings.Builder
+	views.WriteString("\nThe following views are predefined:\n")
+	for _, view := range history.Views {
+		fmt.Fprintf(&views, "  %-16s%s\n", view.Name, view.Description)
+	}
+	sqlCmd.Long += views.String()
+
+	sqlCmd.Flags().StringVarP(&sqlFormat, "format", "f", "table", "output format (table, csv, json, ndjson)")
+	rootCmd.AddCommand(sqlCmd)
+}
+
+func runSQL(cmd *cobra.Command, args []string) error {
+	out, err := newSQLWriter(sqlFormat, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	if err := manager.QuerySQL(context.Background(), args[0], out.header, out.row); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	return out.flush()
+}
+
+// sqlWriter writes the result of a query in one output format
+type sqlWriter interface {
+	header(columns []string) error
+	row(values []interface{}) error
+	flush() error
+}
+
+func newSQLWriter(format string, w io.Writer) (sqlWriter, error) {
+	switch format {
+	case "table":
+		return &sqlTableWriter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}, nil
+	case "csv":
+		return &sqlCSVWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &sqlJSONWriter{w: w}, nil
+	case "ndjson":
+		return &sqlNDJSONWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected table, csv, json or ndjson)", format)
+	}
+}
+
+// sqlValue converts a scanned value to a value that prints well.
+// Drivers return text as []byte and timestamps as time.Time.
+func sqlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Local().Format(time.RFC3339)
+	default:
+		return v
+	}
+}
+
+// sqlTableWriter aligns the result in columns
+type sqlTableWriter struct {
+	w *tabwriter.Writer
+}
+
+// tableEscaper keeps multi-line commands on one row
+var tableEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`)
+
+func (t *sqlTableWriter) header(columns []string) error {
+	_, err := fmt.Fprintln(t.w, strings.ToUpper(strings.Join(columns, "\t")))
+	return err
+}
+
+func (t *sqlTableWriter) row(values []interface{}) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		switch v := sqlValue(v).(type) {
+		case nil:
+			cells[i] = "NULL"
+		case string:
+			cells[i] = tableEscaper.Replace(v)
+		default:
+			cells[i] = fmt.Sprint(v)
+		}
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+	return err
+}
+
+func (t *sqlTableWriter) flush() error {
+	return t.w.Flush()
+}
+
+// sqlNDJSONWriter writes every row as a JSON object on its own line
+type sqlNDJSONWriter struct {
+	w       io.Writer
+	columns []string
+}
+
+func (n *sqlNDJSONWriter) header(columns []string) error {
+	n.columns = columns
+	return nil
+}
+
+func (n *sqlNDJSONWriter) row(values []interface{}) error {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, column := range n.columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(sqlValue(values[i]))
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(n.w, b.String())
+	return err
+}
+
+func (n *sqlNDJSONWriter) flush() error {
+	return nil
+}
+
+// sqlCSVWriter writes the result as CSV with a header row; NULL is written as an empty field
+type sqlCSVWriter struct {
+	w *csv.Writer
+}
+
+func (c *sqlCSVWriter) header(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *sqlCSVWriter) row(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		if v := sqlValue(v); v != nil {
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *sqlCSVWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// sqlJSONWriter writes the result as a JSON array of objects, one per row,
+// with the keys in the order of the columns
+type sqlJSONWriter struct {
+	w       io.Writer
+	columns []string
+	rows    int
+}
+
+func (j *sqlJSONWriter) header(columns []string) error {
+	j.columns = columns
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *sqlJSONWriter) row(values []interface{}) error {
+	var b strings.Builder
+	if j.rows > 0 {
+		b.WriteString(",")
+	}
+	b.WriteString("\n  {")
+	for i, column := range j.columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(sqlValue(values[i]))
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.Write(key)
+		b.WriteString(": ")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	j.rows++
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *sqlJSONWriter) flush() error {
+	end := "]\n"
+	if j.rows > 0 {
+		end = "\n]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
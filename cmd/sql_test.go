@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestSQL(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf("database_path = %q", dbPath)), 0644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	testCommands := []struct {
+		command   string
+		directory string
+		sid       string
+	}{
+		{"make", "/src/app", "s1"},
+		{"make", "/src/app", "s1"},
+		{"git status", "/src/app", "s2"},
+		{"make", "/src/lib", "s2"},
+	}
+	for i, tc := range testCommands {
+		if _, err := manager.AddCommand(tc.command, tc.directory, "", tc.sid, "localhost", "testuser", start.Add(time.Duration(i)*time.Minute), true); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	cfgFile = configPath
+	defer func() { sqlFormat = "table" }()
+
+	run := func(format, query string) (string, error) {
+		sqlFormat = format
+		var buf bytes.Buffer
+		sqlCmd.SetOut(&buf)
+		err := runSQL(sqlCmd, []string{query})
+		return buf.String(), err
+	}
+
+	t.Run("table", func(t *testing.T) {
+		out, err := run("table", "SELECT directory, command, runs FROM commands_by_dir ORDER BY runs DESC, directory, command")
+		if err != nil {
+			t.Fatalf("runSQL failed: %v", err)
+		}
+		expected := "DIRECTORY  COMMAND     RUNS\n/src/app   make        2\n/src/app   git status  1\n/src/lib   make        1\n"
+		if out != expected {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		out, err := run("csv", "SELECT sid, commands, tty FROM sessions JOIN history USING (sid) WHERE command = 'git status'")
+		if err != nil {
+			t.Fatalf("runSQL failed: %v", err)
+		}
+		if out != "sid,commands,tty\ns2,2,\n" {
+			t.Errorf("unexpected output: %q", out)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := run("json", "SELECT command, runs, last_run FROM commands ORDER BY command")
+		if err != nil {
+			t.Fatalf("runSQL failed: %v", err)
+		}
+		var rows []map[string]interface{}
+		if err := json.Unmarshal([]byte(out), &rows); err != nil {
+			t.Fatalf("invalid JSON %q: %v", out, err)
+		}
+		if len(rows) != 2 || rows[1]["command"] != "make" || rows[1]["runs"] != float64(3) {
+			t.Errorf("unexpected rows: %v", rows)
+		}
+		if !strings.Contains(out, `{"command": "git status", "runs": 1, "last_run": `) {
+			t.Errorf("expected keys in column order: %s", out)
+		}
+
+		out, err = run("json", "SELECT * FROM history WHERE command = 'none'")
+		if err != nil {
+			t.Fatalf("runSQL failed: %v", err)
+		}
+		if out != "[]\n" {
+			t.Errorf("expected empty array, got %q", out)
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		for _, query := range []string{
+			"DELETE FROM history",
+			"INSERT INTO history (id, command) VALUES ('x', 'y')",
+			"ATTACH DATABASE '" + filepath.Join(tmpDir, "other.sqlite") + "' AS other; CREATE TABLE other.t (x)",
+		} {
+			if _, err := run("table", query); err == nil {
+				t.Errorf("expected %q to fail", query)
+			}
+		}
+		out, err := run("csv", "SELECT COUNT(*) AS n FROM history")
+		if err != nil {
+			t.Fatalf("runSQL failed: %v", err)
+		}
+		if out != "n\n4\n" {
+			t.Errorf("expected history to be unchanged, got %q", out)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := run("xml", "SELECT 1"); err == nil {
+			t.Error("expected error for unknown format")
+		}
+	})
+}
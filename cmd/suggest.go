@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// suggestCmd represents the suggest command
+var suggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Print the best completion for a command prefix",
+	Long: `Print the most relevant command starting with the given prefix.
+Commands executed in the current directory are preferred over commands from
+other directories, and more recent commands over older ones.
+Nothing is printed if there is no matching command.
+This is used by the zsh-autosuggestions strategy in the zsh integration script.`,
+	RunE: runSuggest,
+}
+
+var (
+	suggestPrefix string
+	suggestDir    string
+)
+
+func init() {
+	suggestCmd.Flags().StringVarP(&suggestPrefix, "prefix", "p", "", "typed command prefix")
+	suggestCmd.Flags().StringVarP(&suggestDir, "directory", "d", "", "directory to suggest for (default is current directory)")
+	rootCmd.AddCommand(suggestCmd)
+}
+
+func runSuggest(cmd *cobra.Command, args []string) error {
+	if suggestPrefix == "" {
+		return nil
+	}
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Suggestions are requested on every keystroke, so the schema is left to
+	// the add hook to check and migrate
+	opts := append(managerOptions(cfg), history.WithoutSchemaCheck())
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	currentDir := suggestDir
+	if currentDir == "" {
+		currentDir, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	command, err := suggestCommand(manager, suggestPrefix, currentDir)
+	if err != nil {
+		return fmt.Errorf("failed to find suggestion: %w", err)
+	}
+	if command != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), command)
+	}
+	return nil
+}
+
+// suggestCommand returns the most recent command starting with prefix, looking in
+// currentDir first. Both lookups are limited to a single row so that they stay fast
+// on large histories.
+func suggestCommand(manager *history.Manager, prefix string, currentDir string) (string, error) {
+	entries, err := manager.Query().WithPrefix(prefix).InDirectory(currentDir).Limit(1).GetEntries()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		entries, err = manager.Query().WithPrefix(prefix).Limit(1).GetEntries()
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	return entries[0].Command, nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestSuggestCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.sqlite")
+	if err := RunMigrations(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	testCommands := []struct {
+		command   string
+		directory string
+	}{
+		{"git status", "/project"},
+		{"git stash", "/other"},
+		{"git_helper", "/other"},
+		{"GIT_DIR=x git log", "/other"},
+		{"gitk", "/project"},
+		{"git diff", "/other"},
+	}
+	for _, tc := range testCommands {
+		if _, err := manager.AddCommand(tc.command, tc.directory, "", "", "localhost", "testuser", time.Now(), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		prefix    string
+		directory string
+		expected  string
+	}{
+		{"current directory first", "git ", "/project", "git status"},
+		{"most recent in current directory", "git", "/project", "gitk"},
+		{"fall back to other directories", "git ", "/elsewhere", "git diff"},
+		{"more specific prefix", "git st", "/other", "git stash"},
+		{"case-sensitive", "GIT", "/project", "GIT_DIR=x git log"},
+		{"underscore is not a wildcard", "git_", "/project", "git_helper"},
+		{"no match", "docker", "/project", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := suggestCommand(manager, tt.prefix, tt.directory)
+			if err != nil {
+				t.Fatalf("suggestCommand failed: %v", err)
+			}
+			if command != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, command)
+			}
+		})
+	}
+}
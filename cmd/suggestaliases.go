@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sett4/duckhist/internal/analytics"
+	"github.com/sett4/duckhist/internal/config"
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+// suggestAliasesCmd represents the suggest-aliases command
+var suggestAliasesCmd = &cobra.Command{
+	Use:   "suggest-aliases",
+	Short: "Suggest shell aliases and functions for frequently used commands",
+	Long: `Mine the command history for long commands that are run often and for
+frequent sequences of commands run one after another in the same shell session,
+and propose alias and function definitions for them.
+Suggestions are ordered by the estimated number of keystrokes they would have saved.
+The output can be pasted into ~/.zshrc after reviewing the proposed names.`,
+	RunE: runSuggestAliases,
+}
+
+var (
+	aliasMinCount    int
+	aliasMinLength   int
+	aliasMaxSequence int
+	aliasLimit       int
+)
+
+func init() {
+	suggestAliasesCmd.Flags().IntVar(&aliasMinCount, "min-count", 5, "minimum number of times a command or sequence was run")
+	suggestAliasesCmd.Flags().IntVar(&aliasMinLength, "min-length", 15, "minimum length of a single command to suggest an alias for")
+	suggestAliasesCmd.Flags().IntVar(&aliasMaxSequence, "max-sequence", 3, "maximum number of commands in a suggested function")
+	suggestAliasesCmd.Flags().IntVarP(&aliasLimit, "limit", "n", 10, "maximum number of suggestions (0 for no limit)")
+	rootCmd.AddCommand(suggestAliasesCmd)
+}
+
+func runSuggestAliases(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager, err := history.NewManagerReadOnly(cfg.DatabasePath, managerOptions(cfg)...)
+	if err != nil {
+		return fmt.Errorf("failed to create history manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			log.Printf("failed to close manager: %v", err)
+		}
+	}()
+
+	miner := analytics.NewAliasMiner(analytics.AliasOptions{
+		MinCount:    aliasMinCount,
+		MinLength:   aliasMinLength,
+		MaxSequence: aliasMaxSequence,
+		Limit:       aliasLimit,
+	})
+	err = manager.Query().OrderByOldestFirst().Each(func(entry history.Entry) error {
+		miner.Add(entry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+	suggestions := miner.Suggestions()
+
+	out := cmd.OutOrStdout()
+	if len(suggestions) == 0 {
+		fmt.Fprintln(out, "# No suggestions found")
+		return nil
+	}
+	for i, suggestion := range suggestions {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		what := "run"
+		if len(suggestion.Commands) > 1 {
+			what = "sequence run"
+		}
+		fmt.Fprintf(out, "# %s %d times, saves ~%s keystrokes\n", what, suggestion.Count, humanize.Comma(int64(suggestion.SavedKeystrokes)))
+		fmt.Fprintln(out, strings.TrimSpace(suggestion.Definition()))
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package analytics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Period is the length of a time-series bucket
+type Period string
+
+const (
+	Hour Period = "hour"
+	Day  Period = "day"
+	Week Period = "week"
+)
+
+// ParsePeriod converts a period name into a Period
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(strings.ToLower(s)); p {
+	case Hour, Day, Week:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown period %q (expected hour, day or week)", s)
+}
+
+// Truncate returns the start of the bucket containing t in the given location.
+// Weeks start on Monday.
+func (p Period) Truncate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch p {
+	case Hour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case Week:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -daysSinceMonday(day))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// next returns the start of the bucket following start
+func (p Period) next(start time.Time) time.Time {
+	switch p {
+	case Hour:
+		return start.Add(time.Hour)
+	case Week:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func daysSinceMonday(t time.Time) int {
+	return (int(t.Weekday()) + 6) % 7
+}
+
+// ActivityPoint is the number of commands executed in one bucket
+type ActivityPoint struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// ActivityCounter counts commands in buckets of a period as they are added,
+// so the history does not have to be held in memory
+type ActivityCounter struct {
+	period      Period
+	loc         *time.Location
+	counts      map[int64]int
+	first, last time.Time
+}
+
+// NewActivityCounter returns a counter for buckets of period in loc
+func NewActivityCounter(period Period, loc *time.Location) *ActivityCounter {
+	return &ActivityCounter{period: period, loc: loc, counts: make(map[int64]int)}
+}
+
+// Add counts a command executed at t
+func (c *ActivityCounter) Add(t time.Time) {
+	start := c.period.Truncate(t, c.loc)
+	if len(c.counts) == 0 || start.Before(c.first) {
+		c.first = start
+	}
+	if len(c.counts) == 0 || start.After(c.last) {
+		c.last = start
+	}
+	c.counts[start.Unix()]++
+}
+
+// Points returns the counts ordered by time, including zero-count buckets
+// between the first and the last active bucket, so they can be plotted directly
+func (c *ActivityCounter) Points() []ActivityPoint {
+	if len(c.counts) == 0 {
+		return nil
+	}
+	var points []ActivityPoint
+	for start := c.first; !start.After(c.last); start = c.period.next(start) {
+		points = append(points, ActivityPoint{Start: start, Count: c.counts[start.Unix()]})
+	}
+	return points
+}
+
+// CountActivity groups entries into buckets of the given period, like ActivityCounter
+func CountActivity(entries []history.Entry, period Period, loc *time.Location) []ActivityPoint {
+	c := NewActivityCounter(period, loc)
+	for _, entry := range entries {
+		c.Add(entry.Timestamp)
+	}
+	return c.Points()
+}
+
+// heatmapLevels are the shades used for the heatmap, from no activity to the busiest days
+var heatmapLevels = []struct {
+	block string
+	color tcell.Color
+}{
+	{"·", tcell.NewHexColor(0x3b3f45)},
+	{"░", tcell.NewHexColor(0x0e4429)},
+	{"▒", tcell.NewHexColor(0x006d32)},
+	{"▓", tcell.NewHexColor(0x26a641)},
+	{"█", tcell.NewHexColor(0x39d353)},
+}
+
+// Heatmap counts commands per day of the weeks ending at end for a
+// calendar-style heatmap. Each column is a week (starting on Monday) and each
+// row a weekday.
+type Heatmap struct {
+	weeks    int
+	firstDay time.Time
+	lastDay  time.Time
+	counts   map[int64]int
+	total    int
+}
+
+// NewHeatmap returns an empty heatmap of the given number of weeks ending at end
+func NewHeatmap(weeks int, end time.Time) (*Heatmap, error) {
+	if weeks <= 0 {
+		return nil, fmt.Errorf("weeks must be positive, got %d", weeks)
+	}
+	loc := end.Location()
+	lastWeek := Week.Truncate(end, loc)
+	return &Heatmap{
+		weeks:    weeks,
+		firstDay: lastWeek.AddDate(0, 0, -7*(weeks-1)),
+		lastDay:  Day.Truncate(end, loc),
+		counts:   make(map[int64]int),
+	}, nil
+}
+
+// Start returns the first day of the heatmap; earlier commands are not counted
+func (h *Heatmap) Start() time.Time {
+	return h.firstDay
+}
+
+// Add counts a command executed at t if it falls within the heatmap
+func (h *Heatmap) Add(t time.Time) {
+	day := Day.Truncate(t, h.firstDay.Location())
+	if day.Before(h.firstDay) || day.After(h.lastDay) {
+		return
+	}
+	h.counts[day.Unix()]++
+	h.total++
+}
+
+// RenderHeatmap writes a calendar-style heatmap of daily command counts ending at end.
+// If color is true, cells are drawn as colored squares using ANSI true color escapes,
+// otherwise Unicode shading blocks are used.
+func RenderHeatmap(w io.Writer, entries []history.Entry, weeks int, end time.Time, color bool) error {
+	h, err := NewHeatmap(weeks, end)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		h.Add(entry.Timestamp)
+	}
+	return h.Render(w, color)
+}
+
+// Render writes the heatmap like RenderHeatmap
+func (h *Heatmap) Render(w io.Writer, color bool) error {
+	weeks, firstDay, lastDay := h.weeks, h.firstDay, h.lastDay
+	maxCount := 0
+	for _, count := range h.counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	var b strings.Builder
+
+	// Month labels above the first week of each month
+	b.WriteString("    ")
+	for col := 0; col < weeks; col++ {
+		weekStart := firstDay.AddDate(0, 0, 7*col)
+		if col == 0 || weekStart.AddDate(0, 0, -7).Month() != weekStart.Month() {
+			label := weekStart.Format("Jan")
+			if col+len(label) <= weeks {
+				b.WriteString(label)
+				col += len(label) - 1
+				continue
+			}
+		}
+		b.WriteString(" ")
+	}
+	b.WriteString("\n")
+
+	weekdays := []string{"Mon", "", "Wed", "", "Fri", "", "Sun"}
+	for row, name := range weekdays {
+		fmt.Fprintf(&b, "%-4s", name)
+		for col := 0; col < weeks; col++ {
+			day := firstDay.AddDate(0, 0, 7*col+row)
+			if day.After(lastDay) {
+				b.WriteString(" ")
+				continue
+			}
+			b.WriteString(heatmapCell(level(h.counts[day.Unix()], maxCount), color))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\n%d commands from %s to %s    Less ", h.total, firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+	for i := range heatmapLevels {
+		b.WriteString(heatmapCell(i, color))
+	}
+	b.WriteString(" More\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// level maps a count to an index of heatmapLevels relative to the busiest day
+func level(count, maxCount int) int {
+	if count == 0 || maxCount == 0 {
+		return 0
+	}
+	steps := len(heatmapLevels) - 1
+	l := (count*steps + maxCount - 1) / maxCount
+	if l < 1 {
+		l = 1
+	}
+	return l
+}
+
+func heatmapCell(l int, color bool) string {
+	shade := heatmapLevels[l]
+	if !color {
+		return shade.block
+	}
+	r, g, b := shade.color.RGB()
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm■\x1b[0m", r, g, b)
+}
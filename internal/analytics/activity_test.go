@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestCountActivity(t *testing.T) {
+	loc := time.UTC
+	at := func(s string) history.Entry {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatalf("failed to parse time: %v", err)
+		}
+		return history.Entry{Timestamp: ts}
+	}
+	entries := []history.Entry{
+		at("2024-01-03 10:15"),
+		at("2024-01-01 09:00"),
+		at("2024-01-01 09:30"),
+		at("2024-01-08 23:59"),
+	}
+
+	t.Run("day", func(t *testing.T) {
+		points := CountActivity(entries, Day, loc)
+		if len(points) != 8 {
+			t.Fatalf("expected 8 days, got %d", len(points))
+		}
+		expected := map[string]int{"2024-01-01": 2, "2024-01-02": 0, "2024-01-03": 1, "2024-01-08": 1}
+		for _, p := range points {
+			if want, ok := expected[p.Start.Format("2006-01-02")]; ok && p.Count != want {
+				t.Errorf("%s: expected %d, got %d", p.Start.Format("2006-01-02"), want, p.Count)
+			}
+		}
+	})
+
+	t.Run("week", func(t *testing.T) {
+		points := CountActivity(entries, Week, loc)
+		if len(points) != 2 {
+			t.Fatalf("expected 2 weeks, got %d", len(points))
+		}
+		if points[0].Start.Weekday() != time.Monday {
+			t.Errorf("expected weeks to start on Monday, got %s", points[0].Start.Weekday())
+		}
+		if points[0].Count != 3 || points[1].Count != 1 {
+			t.Errorf("unexpected weekly counts: %+v", points)
+		}
+	})
+
+	t.Run("hour", func(t *testing.T) {
+		points := CountActivity(entries[1:3], Hour, loc)
+		if len(points) != 1 || points[0].Count != 2 {
+			t.Errorf("expected a single hour with 2 commands, got %+v", points)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if points := CountActivity(nil, Day, loc); points != nil {
+			t.Errorf("expected no points, got %+v", points)
+		}
+	})
+}
+
+func TestParsePeriod(t *testing.T) {
+	if p, err := ParsePeriod("Week"); err != nil || p != Week {
+		t.Errorf("expected week, got %q (%v)", p, err)
+	}
+	if _, err := ParsePeriod("month"); err == nil {
+		t.Error("expected error for unknown period")
+	}
+}
+
+func TestRenderHeatmap(t *testing.T) {
+	end := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC) // Wednesday
+	entries := []history.Entry{
+		{Timestamp: time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2024, 1, 8, 11, 0, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2024, 1, 9, 11, 0, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2023, 1, 1, 11, 0, 0, 0, time.UTC)}, // outside the range
+	}
+
+	var buf bytes.Buffer
+	if err := RenderHeatmap(&buf, entries, 2, end, false); err != nil {
+		t.Fatalf("RenderHeatmap failed: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+
+	// Monday of the second week is the busiest day
+	if lines[1] != "Mon ·█" {
+		t.Errorf("unexpected Monday row: %q", lines[1])
+	}
+	if lines[2] != "    ·▒" {
+		t.Errorf("unexpected Tuesday row: %q", lines[2])
+	}
+	// Days after end are left blank
+	if lines[4] != "    · " {
+		t.Errorf("unexpected Thursday row: %q", lines[4])
+	}
+	if !strings.Contains(buf.String(), "3 commands from 2024-01-01 to 2024-01-10") {
+		t.Errorf("unexpected summary: %s", buf.String())
+	}
+
+	if err := RenderHeatmap(&buf, entries, 0, end, false); err == nil {
+		t.Error("expected error for non-positive weeks")
+	}
+}
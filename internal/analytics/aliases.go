@@ -0,0 +1,242 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/shell"
+)
+
+// AliasOptions controls which commands and sequences are proposed as aliases
+type AliasOptions struct {
+	// MinCount is the number of times a command or sequence must have been run
+	MinCount int
+	// MinLength is the minimum length of a single command to be worth an alias
+	MinLength int
+	// MaxSequence is the maximum number of consecutive commands in a sequence
+	MaxSequence int
+	// Limit is the maximum number of suggestions returned (0 means no limit)
+	Limit int
+}
+
+// AliasSuggestion is a proposed alias (one command) or shell function (a sequence)
+type AliasSuggestion struct {
+	Name            string
+	Commands        []string
+	Count           int
+	SavedKeystrokes int
+}
+
+// Definition returns the shell code defining the suggestion
+func (s AliasSuggestion) Definition() string {
+	if len(s.Commands) == 1 {
+		return fmt.Sprintf("alias %s=%s", s.Name, shell.Quote(s.Commands[0]))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s() {\n", s.Name)
+	for i, command := range s.Commands {
+		b.WriteString("    " + command)
+		if i < len(s.Commands)-1 {
+			b.WriteString(" &&")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// AliasMiner counts long commands and sequences of commands as entries are
+// added, so the history does not have to be held in memory
+type AliasMiner struct {
+	opts           AliasOptions
+	commandCounts  map[string]int
+	sequenceCounts map[string]int
+	// sessions holds the last MaxSequence-1 commands of every session added so far
+	sessions map[string][]string
+}
+
+// NewAliasMiner returns a miner for suggestions matching opts
+func NewAliasMiner(opts AliasOptions) *AliasMiner {
+	if opts.MinCount < 1 {
+		opts.MinCount = 1
+	}
+	return &AliasMiner{
+		opts:           opts,
+		commandCounts:  make(map[string]int),
+		sequenceCounts: make(map[string]int),
+		sessions:       make(map[string][]string),
+	}
+}
+
+// Add adds an entry newer than the ones added before
+func (m *AliasMiner) Add(entry history.Entry) {
+	// Single long commands
+	command := normalizeCommand(entry.Command)
+	if len(command) >= m.opts.MinLength {
+		m.commandCounts[command]++
+	}
+
+	// Sequences of consecutive commands in the same session, counted at their last command
+	if entry.SID == "" || m.opts.MaxSequence < 2 {
+		return
+	}
+	commands := append(m.sessions[entry.SID], command)
+	for n := 2; n <= m.opts.MaxSequence && n <= len(commands); n++ {
+		sequence := commands[len(commands)-n:]
+		if isRepetition(sequence) {
+			continue
+		}
+		m.sequenceCounts[strings.Join(sequence, "\n")]++
+	}
+	if len(commands) >= m.opts.MaxSequence {
+		commands = append([]string(nil), commands[len(commands)-m.opts.MaxSequence+1:]...)
+	}
+	m.sessions[entry.SID] = commands
+}
+
+// SuggestAliases mines entries for long commands that are run often and for
+// frequent sequences of commands within the same session, like AliasMiner.
+// Entries must be ordered from oldest to newest. Suggestions are ordered by the
+// estimated number of keystrokes saved, assuming the history repeats itself.
+func SuggestAliases(entries []history.Entry, opts AliasOptions) []AliasSuggestion {
+	m := NewAliasMiner(opts)
+	for _, entry := range entries {
+		m.Add(entry)
+	}
+	return m.Suggestions()
+}
+
+// Suggestions returns the suggestions for the entries added so far
+func (m *AliasMiner) Suggestions() []AliasSuggestion {
+	opts := m.opts
+	var candidates []AliasSuggestion
+	for command, count := range m.commandCounts {
+		if count >= opts.MinCount {
+			candidates = append(candidates, AliasSuggestion{Commands: []string{command}, Count: count})
+		}
+	}
+
+	sequenceCounts := m.sequenceCounts
+	extended := extensionCounts(sequenceCounts)
+	for key, count := range sequenceCounts {
+		// Every occurrence of a longer sequence containing key also counts a sequence
+		// extending key by one command, so it is enough to look at those
+		if count < opts.MinCount || extended[key] >= count {
+			continue
+		}
+		candidates = append(candidates, AliasSuggestion{Commands: strings.Split(key, "\n"), Count: count})
+	}
+
+	// Name candidates in a stable order so that repeated runs propose the same names
+	sort.Slice(candidates, func(i, j int) bool {
+		return strings.Join(candidates[i].Commands, "\n") < strings.Join(candidates[j].Commands, "\n")
+	})
+	used := make(map[string]bool)
+	for i := range candidates {
+		c := &candidates[i]
+		c.Name = uniqueName(aliasName(c.Commands), used)
+		typed := 0
+		for _, command := range c.Commands {
+			typed += len(command) + 1 // +1 for Enter
+		}
+		c.SavedKeystrokes = (typed - len(c.Name) - 1) * c.Count
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].SavedKeystrokes > candidates[j].SavedKeystrokes
+	})
+
+	var suggestions []AliasSuggestion
+	for _, c := range candidates {
+		if c.SavedKeystrokes <= 0 {
+			continue
+		}
+		suggestions = append(suggestions, c)
+		if opts.Limit > 0 && len(suggestions) == opts.Limit {
+			break
+		}
+	}
+	return suggestions
+}
+
+// normalizeCommand trims the command and collapses runs of whitespace
+func normalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// isRepetition reports whether all commands in the sequence are the same
+func isRepetition(commands []string) bool {
+	for _, command := range commands[1:] {
+		if command != commands[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// extensionCounts maps each sequence to the highest count of the sequences extending
+// it by one command at either end. A sequence whose extension occurs at least as
+// often is subsumed by it, so suggesting it on its own adds nothing.
+func extensionCounts(sequenceCounts map[string]int) map[string]int {
+	extended := make(map[string]int)
+	for key, count := range sequenceCounts {
+		commands := strings.Split(key, "\n")
+		if len(commands) < 3 {
+			// Single commands are not sequences
+			continue
+		}
+		for _, sub := range []string{
+			strings.Join(commands[1:], "\n"),
+			strings.Join(commands[:len(commands)-1], "\n"),
+		} {
+			if count > extended[sub] {
+				extended[sub] = count
+			}
+		}
+	}
+	return extended
+}
+
+// aliasName derives a short name from the initials of the words of the commands,
+// skipping options: "docker compose up -d" becomes "dcu"
+func aliasName(commands []string) string {
+	maxWords := 4
+	if len(commands) > 1 {
+		maxWords = 2
+	}
+
+	var b strings.Builder
+	for _, command := range commands {
+		words := 0
+		for _, word := range strings.Fields(command) {
+			if words == maxWords {
+				break
+			}
+			r := []rune(word)[0]
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				continue
+			}
+			b.WriteRune(unicode.ToLower(r))
+			words++
+		}
+	}
+
+	name := b.String()
+	if len(name) < 2 {
+		name += "x"
+	}
+	return name
+}
+
+// uniqueName appends a number to name until it is not used yet
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
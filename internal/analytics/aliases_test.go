@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestSuggestAliases(t *testing.T) {
+	var entries []history.Entry
+	add := func(sid string, commands ...string) {
+		for _, command := range commands {
+			entries = append(entries, history.Entry{Command: command, SID: sid})
+		}
+	}
+	for i := 0; i < 3; i++ {
+		add("s1", "git add -A", "git commit -v", "ls")
+		add("", "docker compose  up -d --build")
+	}
+	add("s2", "git add -A", "git commit -v")
+	add("s2", "make", "make")
+
+	suggestions := SuggestAliases(entries, AliasOptions{MinCount: 3, MinLength: 15, MaxSequence: 3})
+
+	byName := make(map[string]AliasSuggestion)
+	for _, s := range suggestions {
+		byName[s.Name] = s
+	}
+
+	docker, ok := byName["dcu"]
+	if !ok {
+		t.Fatalf("expected alias for docker compose, got %+v", suggestions)
+	}
+	if docker.Count != 3 || docker.Commands[0] != "docker compose up -d --build" {
+		t.Errorf("unexpected docker suggestion: %+v", docker)
+	}
+	if docker.Definition() != "alias dcu='docker compose up -d --build'" {
+		t.Errorf("unexpected definition: %s", docker.Definition())
+	}
+	// (28 chars + Enter - "dcu" - Enter) * 3
+	if docker.SavedKeystrokes != 75 {
+		t.Errorf("expected 75 saved keystrokes, got %d", docker.SavedKeystrokes)
+	}
+
+	sequence, ok := byName["gagc"]
+	if !ok {
+		t.Fatalf("expected function for git add and commit, got %+v", suggestions)
+	}
+	if sequence.Count != 4 {
+		t.Errorf("expected sequence count 4, got %d", sequence.Count)
+	}
+	if !strings.Contains(sequence.Definition(), "gagc() {\n    git add -A &&\n    git commit -v\n}") {
+		t.Errorf("unexpected definition: %s", sequence.Definition())
+	}
+
+	for _, s := range suggestions {
+		if s.Commands[0] == "make" {
+			t.Errorf("repetitions should not be suggested: %+v", s)
+		}
+		// Always run after "git add -A", so only the longer sequence is suggested
+		if strings.Join(s.Commands, "\n") == "git commit -v\nls" {
+			t.Errorf("subsumed sequences should not be suggested: %+v", s)
+		}
+	}
+	if _, ok := byName["gagcl"]; !ok {
+		t.Errorf("expected function for git add, commit and ls, got %+v", suggestions)
+	}
+
+	for i := 1; i < len(suggestions); i++ {
+		if suggestions[i-1].SavedKeystrokes < suggestions[i].SavedKeystrokes {
+			t.Errorf("suggestions are not ordered by saved keystrokes: %+v", suggestions)
+		}
+	}
+
+	limited := SuggestAliases(entries, AliasOptions{MinCount: 3, MinLength: 15, MaxSequence: 3, Limit: 1})
+	if len(limited) != 1 {
+		t.Errorf("expected 1 suggestion, got %d", len(limited))
+	}
+}
+
+func TestAliasName(t *testing.T) {
+	tests := []struct {
+		commands []string
+		expected string
+	}{
+		{[]string{"docker compose up -d"}, "dcu"},
+		{[]string{"kubectl get pods -n kube-system --watch"}, "kgpk"},
+		{[]string{"ls"}, "lx"},
+		{[]string{"git add -A", "git commit"}, "gagc"},
+	}
+	for _, tt := range tests {
+		if got := aliasName(tt.commands); got != tt.expected {
+			t.Errorf("aliasName(%q): expected %s, got %s", tt.commands, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+// DirectoryBoost is the extra weight given to a transition observed in the
+// directory the prediction is made for
+const DirectoryBoost = 3.0
+
+// Prediction is a command likely to be run next
+type Prediction struct {
+	Command string
+	// Probability is the weighted share of this command among all observed successors
+	Probability float64
+	// Count is the number of times the command followed the context
+	Count int
+}
+
+// successor counts how often a command followed a context, overall and per directory
+type successor struct {
+	count int
+	byDir map[string]int
+}
+
+// Predictor is an n-gram model over normalized commands of the same shell session
+type Predictor struct {
+	order int
+	// ngrams maps a context (the previous commands joined by newlines) to its successors
+	ngrams map[string]map[string]*successor
+	// sessions holds the last order commands of every session added so far
+	sessions map[string][]string
+}
+
+// NewPredictor builds a model from entries ordered from oldest to newest.
+// Entries are grouped into sessions by their sid, or by their tty when no sid was recorded.
+// order is the maximum number of previous commands used as context.
+// More entries can be added with Add.
+func NewPredictor(entries []history.Entry, order int) *Predictor {
+	if order < 1 {
+		order = 1
+	}
+	p := &Predictor{
+		order:    order,
+		ngrams:   make(map[string]map[string]*successor),
+		sessions: make(map[string][]string),
+	}
+	for _, entry := range entries {
+		p.Add(entry)
+	}
+	return p
+}
+
+// Add adds an entry newer than the ones added before to the model
+func (p *Predictor) Add(entry history.Entry) {
+	key := sessionKey(entry)
+	if key == "" {
+		return
+	}
+	command := normalizeCommand(entry.Command)
+	previous := p.sessions[key]
+	for n := 1; n <= p.order && n <= len(previous); n++ {
+		p.observe(strings.Join(previous[len(previous)-n:], "\n"), command, entry.Directory)
+	}
+	previous = append(previous, command)
+	if len(previous) > p.order {
+		previous = previous[1:]
+	}
+	p.sessions[key] = previous
+}
+
+func sessionKey(entry history.Entry) string {
+	if entry.SID != "" {
+		return "sid:" + entry.SID
+	}
+	if entry.TTY != "" {
+		return "tty:" + entry.Hostname + ":" + entry.TTY
+	}
+	return ""
+}
+
+func (p *Predictor) observe(context, command, dir string) {
+	successors, ok := p.ngrams[context]
+	if !ok {
+		successors = make(map[string]*successor)
+		p.ngrams[context] = successors
+	}
+	s, ok := successors[command]
+	if !ok {
+		s = &successor{byDir: make(map[string]int)}
+		successors[command] = s
+	}
+	s.count++
+	s.byDir[dir]++
+}
+
+// Predict returns the commands most likely to follow after (ordered from oldest to newest)
+// in dir. The longest context with observations is used, backing off to shorter ones.
+func (p *Predictor) Predict(after []string, dir string, limit int) []Prediction {
+	context := make([]string, 0, len(after))
+	for _, command := range after {
+		if command = normalizeCommand(command); command != "" {
+			context = append(context, command)
+		}
+	}
+	if len(context) > p.order {
+		context = context[len(context)-p.order:]
+	}
+
+	for n := len(context); n > 0; n-- {
+		successors, ok := p.ngrams[strings.Join(context[len(context)-n:], "\n")]
+		if !ok {
+			continue
+		}
+
+		var predictions []Prediction
+		total := 0.0
+		for command, s := range successors {
+			weight := float64(s.count) + DirectoryBoost*float64(s.byDir[dir])
+			total += weight
+			predictions = append(predictions, Prediction{Command: command, Probability: weight, Count: s.count})
+		}
+		for i := range predictions {
+			predictions[i].Probability /= total
+		}
+		sort.Slice(predictions, func(i, j int) bool {
+			if predictions[i].Probability != predictions[j].Probability {
+				return predictions[i].Probability > predictions[j].Probability
+			}
+			return predictions[i].Command < predictions[j].Command
+		})
+		if limit > 0 && len(predictions) > limit {
+			predictions = predictions[:limit]
+		}
+		return predictions
+	}
+	return nil
+}
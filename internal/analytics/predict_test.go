@@ -0,0 +1,68 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+func TestPredictor(t *testing.T) {
+	var entries []history.Entry
+	add := func(sid, dir string, commands ...string) {
+		for _, command := range commands {
+			entries = append(entries, history.Entry{Command: command, SID: sid, Directory: dir})
+		}
+	}
+	add("s1", "/app", "git add -A", "git commit", "git push")
+	add("s2", "/app", "git add -A", "git commit", "git push")
+	add("s3", "/lib", "git add -A", "git status", "git add -A", "git status")
+	add("s4", "/lib", "make", "git add -A", "git commit --amend")
+	// Entries without session information are ignored
+	add("", "/app", "git add -A", "rm -rf /")
+
+	p := NewPredictor(entries, 2)
+
+	t.Run("most frequent successor", func(t *testing.T) {
+		predictions := p.Predict([]string{"git add -A"}, "/other", 0)
+		if len(predictions) != 3 {
+			t.Fatalf("expected 3 predictions, got %+v", predictions)
+		}
+		if predictions[0].Command != "git commit" || predictions[0].Count != 2 {
+			t.Errorf("expected git commit first, got %+v", predictions[0])
+		}
+		sum := 0.0
+		for _, prediction := range predictions {
+			sum += prediction.Probability
+		}
+		if sum < 0.999 || sum > 1.001 {
+			t.Errorf("expected probabilities to sum up to 1, got %f", sum)
+		}
+	})
+
+	t.Run("directory affinity", func(t *testing.T) {
+		predictions := p.Predict([]string{"git add -A"}, "/lib", 1)
+		if len(predictions) != 1 || predictions[0].Command != "git status" {
+			t.Errorf("expected git status in /lib, got %+v", predictions)
+		}
+	})
+
+	t.Run("longer context", func(t *testing.T) {
+		predictions := p.Predict([]string{"make", "git  add -A"}, "/other", 0)
+		if len(predictions) != 1 || predictions[0].Command != "git commit --amend" {
+			t.Errorf("expected git commit --amend after make, got %+v", predictions)
+		}
+	})
+
+	t.Run("back off to shorter context", func(t *testing.T) {
+		predictions := p.Predict([]string{"ls", "git commit"}, "/other", 0)
+		if len(predictions) != 1 || predictions[0].Command != "git push" {
+			t.Errorf("expected git push, got %+v", predictions)
+		}
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		if predictions := p.Predict([]string{"unknown"}, "/app", 0); predictions != nil {
+			t.Errorf("expected no predictions, got %+v", predictions)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -10,6 +11,39 @@ import (
 type Config struct {
 	DatabasePath              string `mapstructure:"database_path"`
 	CurrentDirectoryHistLimit int    `mapstructure:"current_directory_history_limit"`
+	// DaemonSocket is the Unix socket of the daemon (default is database_path + ".sock")
+	DaemonSocket string `mapstructure:"daemon_socket"`
+	// AutoMigrate applies pending schema migrations when the database is opened
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+	// StorageBackend is the database engine of database_path ("sqlite" or "duckdb")
+	StorageBackend string `mapstructure:"storage_backend"`
+	// DedupPerSession only skips duplicate commands recorded in the same shell session
+	DedupPerSession bool `mapstructure:"dedup_per_session"`
+	// Search holds the settings of the search command
+	Search SearchConfig `mapstructure:"search"`
+}
+
+// SearchConfig is the [search] section of the config file
+type SearchConfig struct {
+	// Case is how keywords match case: "insensitive", "sensitive" or "smart"
+	Case string `mapstructure:"case"`
+	// Height is the number of lines below the prompt the search interface is drawn on,
+	// or 0 to take over the whole terminal
+	Height int `mapstructure:"height"`
+	// Keys binds actions to keys, replacing their default keys
+	Keys map[string][]string `mapstructure:"keys"`
+	// Theme holds the colors of the search interface
+	Theme SearchTheme `mapstructure:"theme"`
+}
+
+// SearchTheme is the [search.theme] section of the config file.
+// Colors are names like "yellow" or hex values like "#ffcc00".
+type SearchTheme struct {
+	SelectionForeground string `mapstructure:"selection_fg"`
+	SelectionBackground string `mapstructure:"selection_bg"`
+	Directory           string `mapstructure:"directory"`
+	Match               string `mapstructure:"match"`
+	Marked              string `mapstructure:"marked"`
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -24,6 +58,13 @@ func LoadConfig(configPath string) (*Config, error) {
 	// Set default values
 	viper.SetDefault("database_path", "~/.duckhist.duckdb")
 	viper.SetDefault("current_directory_history_limit", 5)
+	viper.SetDefault("daemon_socket", "")
+	viper.SetDefault("auto_migrate", false)
+	viper.SetDefault("storage_backend", "sqlite")
+	viper.SetDefault("dedup_per_session", false)
+	viper.SetDefault("search.case", "insensitive")
+	viper.SetDefault("search.theme.match", "red")
+	viper.SetDefault("search.theme.marked", "yellow")
 
 	viper.SetConfigFile(configPath)
 	viper.SetConfigType("toml")
@@ -36,18 +77,36 @@ func LoadConfig(configPath string) (*Config, error) {
 	}
 
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	err := viper.Unmarshal(&config)
+	if err != nil {
 		return nil, err
 	}
 
 	// Expand tilde
-	if config.DatabasePath[:2] == "~/" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, err
-		}
-		config.DatabasePath = filepath.Join(home, config.DatabasePath[2:])
+	config.DatabasePath, err = expandHome(config.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DaemonSocket == "" {
+		config.DaemonSocket = config.DatabasePath + ".sock"
+	}
+	config.DaemonSocket, err = expandHome(config.DaemonSocket)
+	if err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
+
+// expandHome replaces a leading "~/" in path with the home directory
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[2:]), nil
+}
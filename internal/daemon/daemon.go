@@ -0,0 +1,272 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+)
+
+// maxBatchSize is the maximum number of commands written in one transaction
+const maxBatchSize = 256
+
+// writeTimeout is the maximum time a batch may take to be written, e.g. while
+// another process holds the database lock
+const writeTimeout = 10 * time.Second
+
+// ErrNotRunning is returned by the client when no daemon is listening on the socket
+var ErrNotRunning = errors.New("daemon is not running")
+
+// Request is a command to be added to history, sent as one JSON line
+type Request struct {
+	Command    string    `json:"command"`
+	Directory  string    `json:"directory"`
+	TTY        string    `json:"tty"`
+	SID        string    `json:"sid"`
+	Hostname   string    `json:"hostname"`
+	Username   string    `json:"username"`
+	ExecutedAt time.Time `json:"executed_at"`
+	NoDedup    bool      `json:"no_dedup"`
+}
+
+// Response is the result of a Request, sent as one JSON line
+type Response struct {
+	Duplicate bool   `json:"duplicate"`
+	Error     string `json:"error,omitempty"`
+}
+
+// pending is a request waiting to be written by the writer goroutine
+type pending struct {
+	record history.CommandRecord
+	reply  chan Response
+}
+
+// Server accepts requests on a Unix socket and writes them through a single Manager.
+// Requests arriving while a batch is being written are written together in the
+// next transaction.
+type Server struct {
+	manager    *history.Manager
+	socketPath string
+	requests   chan pending
+}
+
+// NewServer creates a new Server writing to manager
+func NewServer(manager *history.Manager, socketPath string) *Server {
+	return &Server{
+		manager:    manager,
+		socketPath: socketPath,
+		requests:   make(chan pending, maxBatchSize),
+	}
+}
+
+// Serve listens on the socket until ctx is canceled.
+// A stale socket file left by a crashed daemon is removed, but Serve fails if
+// another daemon is still listening on it.
+func (s *Server) Serve(ctx context.Context) error {
+	if _, err := os.Stat(s.socketPath); err == nil {
+		if conn, err := net.DialTimeout("unix", s.socketPath, time.Second); err == nil {
+			if err := conn.Close(); err != nil {
+				log.Printf("failed to close connection: %v", err)
+			}
+			return fmt.Errorf("another daemon is already listening on %s", s.socketPath)
+		}
+		if err := os.Remove(s.socketPath); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	// Only the owner may add commands to history
+	if err := os.Chmod(s.socketPath, 0600); err != nil {
+		if closeErr := listener.Close(); closeErr != nil {
+			log.Printf("failed to close listener: %v", closeErr)
+		}
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	writerDone := make(chan struct{})
+	writerCtx, stopWriter := context.WithCancel(context.Background())
+	go func() {
+		s.writeLoop(writerCtx)
+		close(writerDone)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		if err := listener.Close(); err != nil {
+			log.Printf("failed to close listener: %v", err)
+		}
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("failed to accept connection: %v", err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(ctx, conn)
+		}()
+	}
+
+	// Let connections finish their requests before stopping the writer
+	wg.Wait()
+	stopWriter()
+	<-writerDone
+	return nil
+}
+
+// handle reads requests from conn until it is closed
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("failed to close connection: %v", err)
+		}
+	}()
+
+	// Unblock the read below when the daemon shuts down
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := conn.SetReadDeadline(time.Now()); err != nil {
+				log.Printf("failed to interrupt connection: %v", err)
+			}
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			resp = s.submit(ctx, req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			log.Printf("failed to write response: %v", err)
+			return
+		}
+	}
+}
+
+// submit queues a request for the writer and waits for the result
+func (s *Server) submit(ctx context.Context, req Request) Response {
+	p := pending{
+		record: history.CommandRecord{
+			Command:    req.Command,
+			Directory:  req.Directory,
+			TTY:        req.TTY,
+			SID:        req.SID,
+			Hostname:   req.Hostname,
+			Username:   req.Username,
+			ExecutedAt: req.ExecutedAt,
+			NoDedup:    req.NoDedup,
+		},
+		reply: make(chan Response, 1),
+	}
+
+	select {
+	case s.requests <- p:
+	case <-ctx.Done():
+		return Response{Error: "daemon is shutting down"}
+	}
+	return <-p.reply
+}
+
+// writeLoop writes queued requests until ctx is canceled.
+// It takes everything queued at the time it becomes idle as one batch.
+func (s *Server) writeLoop(ctx context.Context) {
+	for {
+		var batch []pending
+		select {
+		case p := <-s.requests:
+			batch = append(batch, p)
+		case <-ctx.Done():
+			return
+		}
+
+	drain:
+		for len(batch) < maxBatchSize {
+			select {
+			case p := <-s.requests:
+				batch = append(batch, p)
+			default:
+				break drain
+			}
+		}
+
+		s.writeBatch(batch)
+	}
+}
+
+func (s *Server) writeBatch(batch []pending) {
+	records := make([]history.CommandRecord, len(batch))
+	for i, p := range batch {
+		records[i] = p.record
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	dups, err := s.manager.AddCommandsContext(ctx, records)
+	for i, p := range batch {
+		if err != nil {
+			p.reply <- Response{Error: err.Error()}
+		} else {
+			p.reply <- Response{Duplicate: dups[i]}
+		}
+	}
+}
+
+// Add sends a request to the daemon listening on socketPath and returns whether
+// the command was skipped as a duplicate. It returns ErrNotRunning if no daemon
+// accepts the connection, so that callers can fall back to writing directly.
+func Add(socketPath string, req Request, timeout time.Duration) (bool, error) {
+	conn, err := net.DialTimeout("unix", socketPath, timeout)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("failed to close connection: %v", err)
+		}
+	}()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return false, err
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return false, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.Error != "" {
+		return false, errors.New(resp.Error)
+	}
+	return resp.Duplicate, nil
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/history"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestManager(t *testing.T) *history.Manager {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE history (
+		id VARCHAR(26) PRIMARY KEY,
+		command TEXT,
+		executed_at TIMESTAMP,
+		executing_host TEXT,
+		executing_dir TEXT,
+		executing_user TEXT,
+		tty TEXT,
+		sid TEXT
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create history table: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	// The table is created without migrations, so a schema version warning is printed
+	manager, err := history.NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create history manager: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	})
+	return manager
+}
+
+func TestServer(t *testing.T) {
+	manager := newTestManager(t)
+	socketPath := filepath.Join(t.TempDir(), "duckhist.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- NewServer(manager, socketPath).Serve(ctx)
+	}()
+
+	// Wait for the socket to accept connections
+	request := Request{Command: "ls", Directory: "/tmp", Hostname: "localhost", Username: "testuser", ExecutedAt: time.Now()}
+	var err error
+	for i := 0; i < 100; i++ {
+		if _, err = Add(socketPath, request, time.Second); !errors.Is(err, ErrNotRunning) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	t.Run("duplicate", func(t *testing.T) {
+		isDup, err := Add(socketPath, request, time.Second)
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if !isDup {
+			t.Error("expected command to be duplicate")
+		}
+	})
+
+	t.Run("concurrent requests", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 50)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				req := request
+				req.Command = fmt.Sprintf("echo %d", i)
+				if _, err := Add(socketPath, req, 5*time.Second); err != nil {
+					errs <- err
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("Add failed: %v", err)
+		}
+
+		entries, err := manager.Query().GetEntries()
+		if err != nil {
+			t.Fatalf("GetEntries failed: %v", err)
+		}
+		if len(entries) != 51 {
+			t.Errorf("expected 51 entries, got %d", len(entries))
+		}
+	})
+
+	t.Run("second daemon", func(t *testing.T) {
+		if err := NewServer(manager, socketPath).Serve(ctx); err == nil {
+			t.Error("expected error when another daemon is listening")
+		}
+	})
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after cancel")
+	}
+
+	if _, err := Add(socketPath, request, time.Second); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning after shutdown, got %v", err)
+	}
+}
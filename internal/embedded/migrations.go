@@ -12,3 +12,12 @@ var MigrationsFS embed.FS
 func GetMigrationsFS() fs.FS {
 	return MigrationsFS
 }
+
+//go:embed migrations_duckdb/*.sql
+var DuckDBMigrationsFS embed.FS
+
+// GetDuckDBMigrationsFS returns the embedded filesystem containing the migration
+// files of the DuckDB backend
+func GetDuckDBMigrationsFS() fs.FS {
+	return DuckDBMigrationsFS
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_history_command;
+DROP TABLE IF EXISTS history;
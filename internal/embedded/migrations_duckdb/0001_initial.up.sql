@@ -0,0 +1,12 @@
+-- The DuckDB schema starts at the state of SQLite migration 0004
+CREATE TABLE IF NOT EXISTS history (
+    id VARCHAR(26) PRIMARY KEY,
+    command VARCHAR,
+    executed_at TIMESTAMP,
+    executing_host VARCHAR,
+    executing_dir VARCHAR,
+    executing_user VARCHAR,
+    tty VARCHAR,
+    sid VARCHAR
+);
+CREATE INDEX IF NOT EXISTS idx_history_command ON history (command);
@@ -11,3 +11,11 @@ var ZshIntegrationScript string
 func GetZshIntegrationScript() string {
 	return ZshIntegrationScript
 }
+
+//go:embed scripts/fzf-duckhist.zsh
+var FzfWidgetScript string
+
+// GetFzfWidgetScript returns the content of the zsh script binding Ctrl-R to fzf
+func GetFzfWidgetScript() string {
+	return FzfWidgetScript
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBackend is the storage backend used when none is configured
+const DefaultBackend = "sqlite"
+
+// Backend is a database engine the history is stored in.
+// All backends use the same history table, so queries are shared and only the
+// parts of SQL that differ between engines are provided by the backend.
+type Backend interface {
+	// Open opens the database at path. A read-only database must not be modified.
+	Open(path string, readOnly bool) (*sql.DB, error)
+	// CheckSchema reports whether the schema of db is up to date, along with
+	// the current and the required schema version
+	CheckSchema(db *sql.DB) (bool, int, int, error)
+	// Migrate applies pending schema migrations to the database at path.
+	// It is called without any connection of this process open on the database.
+	Migrate(path string) error
+
+	// ContainsCondition returns a condition matching commands containing substr
+	// literally, ignoring case unless caseSensitive is set, and the arguments of
+	// its placeholders
+	ContainsCondition(substr string, caseSensitive bool) (string, []interface{})
+	// PrefixCondition returns a condition matching commands starting with prefix
+	// and the arguments of its placeholders
+	PrefixCondition(prefix string) (string, []interface{})
+	// RegexpCondition returns a condition matching commands containing a match of
+	// the Go regular expression pattern, and the arguments of its placeholders
+	RegexpCondition(pattern string) (string, []interface{})
+	// NoLimit returns the clause needed before OFFSET when there is no limit
+	NoLimit() string
+	// TimeCondition returns a condition comparing executed_at with t using the
+	// comparison operator op, and the arguments of its placeholders
+	TimeCondition(op string, t time.Time) (string, []interface{})
+	// QueryOnly returns a statement keeping a connection from writing, or an
+	// empty string if a read-only database already rejects every write
+	QueryOnly() string
+}
+
+// backends are the available storage backends by name. Backends with large
+// dependencies are only compiled in with a build tag and register themselves here.
+var backends = map[string]Backend{
+	DefaultBackend: sqliteBackend{},
+}
+
+// optionalBackends are backends that are not part of every build, with the build tag enabling them
+var optionalBackends = map[string]string{
+	"duckdb": "duckdb",
+}
+
+// LookupBackend returns the storage backend with the given name.
+// An empty name selects DefaultBackend.
+func LookupBackend(name string) (Backend, error) {
+	if name == "" {
+		name = DefaultBackend
+	}
+	if backend, ok := backends[name]; ok {
+		return backend, nil
+	}
+	if tag, ok := optionalBackends[name]; ok {
+		return nil, fmt.Errorf("storage backend %q is not included in this build; rebuild duckhist with -tags %s", name, tag)
+	}
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown storage backend %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Migrate applies pending schema migrations to the database at path using the named backend
+func Migrate(backendName string, path string) error {
+	backend, err := LookupBackend(backendName)
+	if err != nil {
+		return err
+	}
+	return backend.Migrate(path)
+}
@@ -0,0 +1,111 @@
+//go:build duckdb
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sett4/duckhist/internal/embedded"
+	"github.com/sett4/duckhist/internal/migrate"
+
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+// LatestDuckDBSchemaVersion is the version of the newest embedded DuckDB migration
+const LatestDuckDBSchemaVersion = 1
+
+// duckdbLockTimeout is how long opening waits for another process to close the database.
+// DuckDB allows only one process to open a database file while it is written.
+const duckdbLockTimeout = 2 * time.Second
+
+func init() {
+	backends["duckdb"] = duckdbBackend{}
+}
+
+// duckdbBackend stores the history in a DuckDB database, which is faster for
+// analytical queries over large histories
+type duckdbBackend struct{}
+
+func (duckdbBackend) Open(path string, readOnly bool) (*sql.DB, error) {
+	dsn := path
+	if readOnly {
+		dsn += "?access_mode=READ_ONLY"
+	}
+
+	deadline := time.Now().Add(duckdbLockTimeout)
+	for {
+		// The database file is opened and locked immediately
+		db, err := sql.Open("duckdb", dsn)
+		if err == nil || !strings.Contains(err.Error(), "Could not set lock") || time.Now().After(deadline) {
+			return db, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (duckdbBackend) CheckSchema(db *sql.DB) (bool, int, int, error) {
+	var tableExists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'schema_migrations')").Scan(&tableExists)
+	if err != nil {
+		return false, 0, LatestDuckDBSchemaVersion, fmt.Errorf("failed to check if schema_migrations table exists: %w", err)
+	}
+	if !tableExists {
+		return false, 0, LatestDuckDBSchemaVersion, nil
+	}
+
+	var current int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return false, 0, LatestDuckDBSchemaVersion, fmt.Errorf("failed to get current schema version: %w", err)
+	}
+	return current == LatestDuckDBSchemaVersion, current, LatestDuckDBSchemaVersion, nil
+}
+
+func (b duckdbBackend) Migrate(path string) error {
+	return migrate.WithLock(path, func() error {
+		db, err := b.Open(path, false)
+		if err != nil {
+			return err
+		}
+		_, err = migrate.UpSQL(db, embedded.GetDuckDBMigrationsFS(), "migrations_duckdb")
+		if closeErr := db.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// ContainsCondition uses ILIKE, which unlike LIKE ignores case in DuckDB
+func (duckdbBackend) ContainsCondition(substr string, caseSensitive bool) (string, []interface{}) {
+	if caseSensitive {
+		return "contains(command, ?)", []interface{}{substr}
+	}
+	return `command ILIKE ? ESCAPE '\'`, []interface{}{containsPattern(substr)}
+}
+
+func (duckdbBackend) PrefixCondition(prefix string) (string, []interface{}) {
+	// DuckDB rejects strings that are not valid UTF-8, such as prefix + "\xff"
+	return "starts_with(command, ?)", []interface{}{prefix}
+}
+
+// RegexpCondition uses regexp_matches, which like Go uses RE2 syntax
+func (duckdbBackend) RegexpCondition(pattern string) (string, []interface{}) {
+	return "regexp_matches(command, ?)", []interface{}{pattern}
+}
+
+func (duckdbBackend) TimeCondition(op string, t time.Time) (string, []interface{}) {
+	return "executed_at " + op + " ?", []interface{}{t.UTC()}
+}
+
+// NoLimit returns an empty clause because DuckDB accepts OFFSET without LIMIT
+func (duckdbBackend) NoLimit() string {
+	return ""
+}
+
+// QueryOnly returns an empty statement because a database opened with
+// access_mode=READ_ONLY rejects every write
+func (duckdbBackend) QueryOnly() string {
+	return ""
+}
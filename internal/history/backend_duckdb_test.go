@@ -0,0 +1,24 @@
+//go:build duckdb
+
+package history
+
+import (
+	"testing"
+
+	"github.com/sett4/duckhist/internal/embedded"
+	"github.com/sett4/duckhist/internal/migrate"
+)
+
+func TestDuckDBBackend(t *testing.T) {
+	testBackend(t, "duckdb")
+}
+
+func TestLatestDuckDBSchemaVersion(t *testing.T) {
+	version, err := migrate.LatestVersion(embedded.GetDuckDBMigrationsFS(), "migrations_duckdb")
+	if err != nil {
+		t.Fatalf("LatestVersion failed: %v", err)
+	}
+	if LatestDuckDBSchemaVersion != version {
+		t.Errorf("LatestDuckDBSchemaVersion is %d but the newest embedded migration is %d", LatestDuckDBSchemaVersion, version)
+	}
+}
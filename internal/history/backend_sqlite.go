@@ -0,0 +1,133 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sett4/duckhist/internal/migrate"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriverName is the sqlite3 driver with the functions of duckhist registered
+const sqliteDriverName = "sqlite3_duckhist"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// SQLite evaluates X REGEXP Y as regexp(Y, X)
+			if err := conn.RegisterFunc("regexp", sqliteRegexp, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("casefold", foldCase, true)
+		},
+	})
+}
+
+// sqliteRegexpCache holds compiled patterns, because the REGEXP function is
+// called once per row with the same pattern
+var sqliteRegexpCache = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: map[string]*regexp.Regexp{}}
+
+// sqliteRegexpCacheSize is the number of patterns kept compiled
+const sqliteRegexpCacheSize = 16
+
+// sqliteRegexp reports whether s contains a match of the Go regular expression pattern
+func sqliteRegexp(pattern, s string) (bool, error) {
+	sqliteRegexpCache.Lock()
+	re, ok := sqliteRegexpCache.patterns[pattern]
+	if !ok {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			sqliteRegexpCache.Unlock()
+			return false, err
+		}
+		if len(sqliteRegexpCache.patterns) >= sqliteRegexpCacheSize {
+			sqliteRegexpCache.patterns = map[string]*regexp.Regexp{}
+		}
+		sqliteRegexpCache.patterns[pattern] = re
+	}
+	sqliteRegexpCache.Unlock()
+	return re.MatchString(s), nil
+}
+
+// uriEscaper escapes the characters with a special meaning in the path of an SQLite URI
+var uriEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+// sqliteBackend stores the history in an SQLite database
+type sqliteBackend struct{}
+
+func (sqliteBackend) Open(path string, readOnly bool) (*sql.DB, error) {
+	if readOnly {
+		// mode=ro is only honored for file: URIs
+		return sql.Open(sqliteDriverName, "file:"+uriEscaper.Replace(path)+"?mode=ro")
+	}
+
+	db, err := sql.Open(sqliteDriverName, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enable foreign key constraints and WAL mode
+	if _, err := db.Exec("PRAGMA foreign_keys = ON; PRAGMA journal_mode = WAL;"); err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			return nil, fmt.Errorf("failed to enable PRAGMA and close DB: %v, close error: %v", err, closeErr)
+		}
+		return nil, err
+	}
+	return db, nil
+}
+
+// QueryOnly returns a pragma rejecting writes, including writes to attached databases
+func (sqliteBackend) QueryOnly() string {
+	return "PRAGMA query_only = ON"
+}
+
+func (sqliteBackend) CheckSchema(db *sql.DB) (bool, int, int, error) {
+	return migrate.CheckSchemaVersion(db)
+}
+
+func (sqliteBackend) Migrate(path string) error {
+	return migrate.UpLocked(path)
+}
+
+// ContainsCondition uses LIKE, which ignores only ASCII case in SQLite. Other
+// text is compared after folding case with the casefold function.
+func (sqliteBackend) ContainsCondition(substr string, caseSensitive bool) (string, []interface{}) {
+	if caseSensitive {
+		return "instr(command, ?) > 0", []interface{}{substr}
+	}
+	if isASCII(substr) {
+		return `command LIKE ? ESCAPE '\'`, []interface{}{containsPattern(substr)}
+	}
+	return `casefold(command) LIKE ? ESCAPE '\'`, []interface{}{containsPattern(foldCase(substr))}
+}
+
+func (sqliteBackend) PrefixCondition(prefix string) (string, []interface{}) {
+	// No UTF-8 encoded command contains the byte 0xff, so every command starting
+	// with prefix sorts between prefix and prefix + "\xff". Unlike LIKE, this
+	// range can use the index on command.
+	return "command >= ? AND command < ?", []interface{}{prefix, prefix + "\xff"}
+}
+
+func (sqliteBackend) RegexpCondition(pattern string) (string, []interface{}) {
+	return "command REGEXP ?", []interface{}{pattern}
+}
+
+// TimeCondition compares julian day numbers, because timestamps are stored as text
+// with the UTC offset they were recorded with and do not sort chronologically
+func (sqliteBackend) TimeCondition(op string, t time.Time) (string, []interface{}) {
+	return "julianday(executed_at) " + op + " julianday(?)", []interface{}{t.UTC().Format("2006-01-02 15:04:05.000")}
+}
+
+// NoLimit returns LIMIT -1 because SQLite requires a LIMIT clause before OFFSET
+func (sqliteBackend) NoLimit() string {
+	return " LIMIT -1"
+}
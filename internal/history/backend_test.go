@@ -0,0 +1,290 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mustParseFilter parses a filter relative to /src at 2024-05-02 12:00 in loc
+func mustParseFilter(t *testing.T, input string, loc *time.Location) *Filter {
+	t.Helper()
+	f, err := ParseFilter(input, time.Date(2024, 5, 2, 12, 0, 0, 0, loc), "/src")
+	if err != nil {
+		t.Fatalf("ParseFilter(%q) failed: %v", input, err)
+	}
+	return f
+}
+
+// testBackend runs the queries of Manager against the named backend
+func testBackend(t *testing.T, name string) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+
+	// A missing database is created by auto migration
+	manager, err := NewManagerReadWrite(dbPath, WithBackend(name), WithAutoMigrate(true))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	jst := time.FixedZone("JST", 9*60*60)
+	records := []CommandRecord{
+		{Command: "git status", Directory: "/src/app", SID: "s1"},
+		{Command: "GIT LOG", Directory: "/src", SID: "s1"},
+		{Command: "make", Directory: "/src/app/sub", SID: "s2"},
+		{Command: "git status", Directory: "/src/app", SID: "s2"},
+	}
+	for i := range records {
+		records[i].Hostname = "localhost"
+		records[i].Username = "testuser"
+		records[i].ExecutedAt = start.Add(time.Duration(i) * time.Minute)
+		if i%2 == 1 {
+			// Times are compared as instants whatever zone they were recorded in
+			records[i].ExecutedAt = records[i].ExecutedAt.In(jst)
+		}
+	}
+	dups, err := manager.AddCommands(records)
+	if err != nil {
+		t.Fatalf("AddCommands failed: %v", err)
+	}
+	if !dups[3] {
+		t.Error("expected last command to be a duplicate")
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("failed to close manager: %v", err)
+	}
+
+	manager, err = NewManagerReadOnly(dbPath, WithBackend(name))
+	if err != nil {
+		t.Fatalf("failed to create read-only manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		query    *HistoryQuery
+		expected string
+	}{
+		{"all", manager.Query(), "make,GIT LOG,git status"},
+		{"search ignores case", manager.Query().Search("git"), "GIT LOG,git status"},
+		{"prefix", manager.Query().WithPrefix("git "), "git status"},
+		{"under directory", manager.Query().UnderDirectory("/src/app"), "make,git status"},
+		{"offset without limit", manager.Query().Offset(1), "GIT LOG,git status"},
+		{"current dir first", manager.Query().OrderByCurrentDirFirst("/src").Limit(2), "GIT LOG,make"},
+		{"regexp", manager.Query().Regexp(regexp.MustCompile(`^git (status|log)$`)), "git status"},
+		{"regexp flags", manager.Query().Regexp(regexp.MustCompile(`(?i)^git l|^m.k`)), "make,GIT LOG"},
+		{"match keyword and dir", manager.Query().Match(mustParseFilter(t, "git dir:app", jst)), "git status"},
+		{"match after", manager.Query().Match(mustParseFilter(t, "after:2024-05-01T18:01", jst)), "make,GIT LOG"},
+		{"match before", manager.Query().Match(mustParseFilter(t, "before:2024-05-01T09:01:30Z", jst)), "GIT LOG,git status"},
+		{"since and until", manager.Query().Since(start.Add(time.Minute).In(jst)).Until(start.Add(2 * time.Minute)), "GIT LOG"},
+		{"match negation", manager.Query().Match(mustParseFilter(t, "-session:s1", jst)), "make"},
+		{"match or", manager.Query().Match(mustParseFilter(t, "make OR log -dir:/src/app", jst)), "make,GIT LOG"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := tt.query.GetEntries()
+			if err != nil {
+				t.Fatalf("GetEntries failed: %v", err)
+			}
+			var commands []string
+			for _, entry := range entries {
+				commands = append(commands, entry.Command)
+			}
+			if strings.Join(commands, ",") != tt.expected {
+				t.Errorf("expected %s, got %v", tt.expected, commands)
+			}
+		})
+	}
+
+	t.Run("entry fields", func(t *testing.T) {
+		entries, err := manager.Query().OrderByOldestFirst().Limit(1).GetEntries()
+		if err != nil {
+			t.Fatalf("GetEntries failed: %v", err)
+		}
+		if len(entries) != 1 || !entries[0].Timestamp.Equal(start) || entries[0].SID != "s1" || entries[0].TTY != "" {
+			t.Errorf("unexpected entry: %+v", entries)
+		}
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		var columns []string
+		var rows [][]interface{}
+		err := manager.QuerySQL(context.Background(), "SELECT sid, commands FROM sessions ORDER BY sid",
+			func(c []string) error {
+				columns = c
+				return nil
+			},
+			func(values []interface{}) error {
+				rows = append(rows, append([]interface{}{}, values...))
+				return nil
+			})
+		if err != nil {
+			t.Fatalf("QuerySQL failed: %v", err)
+		}
+		if strings.Join(columns, ",") != "sid,commands" || len(rows) != 2 || fmt.Sprint(rows[0]) != "[s1 2]" {
+			t.Errorf("unexpected result: %v %v", columns, rows)
+		}
+
+		discard := func([]string) error { return nil }
+		if err := manager.QuerySQL(context.Background(), "DELETE FROM history", discard, func([]interface{}) error { return nil }); err == nil {
+			t.Error("expected writes to fail")
+		}
+		// Connections with the views are not reused by other queries
+		if _, err := manager.Query().Limit(1).GetEntries(); err != nil {
+			t.Errorf("GetEntries failed after QuerySQL: %v", err)
+		}
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		sessions, err := manager.ListSessions(10)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if len(sessions) != 2 || sessions[0].SID != "s2" || sessions[1].Count != 2 {
+			t.Errorf("unexpected sessions: %+v", sessions)
+		}
+	})
+
+	t.Run("case modes", func(t *testing.T) {
+		testCaseModes(t, name)
+	})
+
+	t.Run("distinct commands", func(t *testing.T) {
+		testDistinctCommands(t, name)
+	})
+}
+
+// testDistinctCommands runs queries for distinct commands against the named backend
+func testDistinctCommands(t *testing.T, name string) {
+	t.Helper()
+	manager, err := NewManagerReadWrite(filepath.Join(t.TempDir(), "distinct.db"), WithBackend(name), WithAutoMigrate(true))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+	for i, r := range []struct{ command, dir string }{
+		{"ls", "/a"}, {"make", "/b"}, {"ls", "/b"}, {"make", "/a"}, {"git", "/b"},
+	} {
+		if _, err := manager.AddCommand(r.command, r.dir, "", "", "localhost", "testuser", time.Unix(int64(i), 0), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    *HistoryQuery
+		expected string
+	}{
+		{"latest first", manager.Query().DistinctCommands(), "git /b,make /a,ls /b"},
+		{"current dir first", manager.Query().DistinctCommands().OrderByCurrentDirFirst("/a"), "make /a,ls /a,git /b"},
+		{"offset", manager.Query().DistinctCommands().OrderByCurrentDirFirst("/a").Offset(1), "ls /a,git /b"},
+		{"in directory", manager.Query().DistinctCommands().InDirectory("/b").Limit(2), "git /b,ls /b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []string
+			err := tt.query.Each(func(entry Entry) error {
+				entries = append(entries, entry.Command+" "+entry.Directory)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Each failed: %v", err)
+			}
+			if strings.Join(entries, ",") != tt.expected {
+				t.Errorf("expected %s, got %v", tt.expected, entries)
+			}
+		})
+	}
+
+	count, err := manager.Query().DistinctCommands().Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 commands, got %d", count)
+	}
+}
+
+// testCaseModes runs keyword searches in every case mode against the named backend
+func testCaseModes(t *testing.T, name string) {
+	t.Helper()
+	manager, err := NewManagerReadWrite(filepath.Join(t.TempDir(), "case.db"), WithBackend(name), WithAutoMigrate(true))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+	for i, command := range []string{"ÄPFEL kaufen", "äpfel essen", "Git push", "git pull", "100% done", "1000 done", "a_b", "axb", `C:\tmp`} {
+		if _, err := manager.AddCommand(command, "/", "", "", "localhost", "testuser", time.Unix(int64(i), 0), false); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+
+	tests := []struct {
+		term     string
+		mode     CaseMode
+		expected string
+	}{
+		{"git", CaseInsensitive, "git pull,Git push"},
+		{"äpfel", CaseInsensitive, "äpfel essen,ÄPFEL kaufen"},
+		{"Äpfel", CaseInsensitive, "äpfel essen,ÄPFEL kaufen"},
+		{"Git", CaseSensitive, "Git push"},
+		{"äpfel", CaseSensitive, "äpfel essen"},
+		{"git", CaseSmart, "git pull,Git push"},
+		{"äpfel", CaseSmart, "äpfel essen,ÄPFEL kaufen"},
+		{"ÄPFEL", CaseSmart, "ÄPFEL kaufen"},
+		{"git Pu", CaseSmart, ""},
+		{"0%", CaseInsensitive, "100% done"},
+		{"a_b", CaseInsensitive, "a_b"},
+		{`c:\`, CaseInsensitive, `C:\tmp`},
+		{"0%", CaseSensitive, "100% done"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String()+" "+tt.term, func(t *testing.T) {
+			for _, q := range []*HistoryQuery{
+				manager.Query().SearchWithCase(tt.term, tt.mode),
+				manager.Query().Match(&Filter{Case: tt.mode, groups: [][]filterTerm{{{value: tt.term}}}}),
+			} {
+				entries, err := q.GetEntries()
+				if err != nil {
+					t.Fatalf("GetEntries failed: %v", err)
+				}
+				var commands []string
+				for _, entry := range entries {
+					commands = append(commands, entry.Command)
+				}
+				if strings.Join(commands, ",") != tt.expected {
+					t.Errorf("expected %s, got %v", tt.expected, commands)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteBackend(t *testing.T) {
+	testBackend(t, DefaultBackend)
+}
+
+func TestLookupBackend(t *testing.T) {
+	if _, err := LookupBackend(""); err != nil {
+		t.Errorf("expected default backend, got %v", err)
+	}
+	if _, err := LookupBackend("postgres"); err == nil || !strings.Contains(err.Error(), "unknown storage backend") {
+		t.Errorf("expected unknown backend error, got %v", err)
+	}
+}
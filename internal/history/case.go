@@ -0,0 +1,101 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CaseMode controls how search keywords match the case of commands
+type CaseMode int
+
+const (
+	// CaseInsensitive ignores case, folding non-ASCII letters with Unicode rules
+	CaseInsensitive CaseMode = iota
+	// CaseSensitive matches case exactly
+	CaseSensitive
+	// CaseSmart matches a keyword case-sensitively if it contains an uppercase
+	// letter and ignores case otherwise
+	CaseSmart
+)
+
+// caseModeNames are the names of the case modes used in flags and the config file
+var caseModeNames = []string{"insensitive", "sensitive", "smart"}
+
+// ParseCaseMode returns the case mode with the given name.
+// An empty name selects CaseInsensitive.
+func ParseCaseMode(name string) (CaseMode, error) {
+	if name == "" {
+		return CaseInsensitive, nil
+	}
+	for i, n := range caseModeNames {
+		if n == name {
+			return CaseMode(i), nil
+		}
+	}
+	return CaseInsensitive, fmt.Errorf("unknown case mode %q (expected %s)", name, strings.Join(caseModeNames, ", "))
+}
+
+func (m CaseMode) String() string {
+	if m < 0 || int(m) >= len(caseModeNames) {
+		return fmt.Sprintf("CaseMode(%d)", int(m))
+	}
+	return caseModeNames[m]
+}
+
+// Next returns the mode following m, cycling through all modes
+func (m CaseMode) Next() CaseMode {
+	return (m + 1) % CaseMode(len(caseModeNames))
+}
+
+// Sensitive reports whether keyword is matched case-sensitively in mode m
+func (m CaseMode) Sensitive(keyword string) bool {
+	switch m {
+	case CaseSensitive:
+		return true
+	case CaseSmart:
+		return strings.IndexFunc(keyword, unicode.IsUpper) >= 0
+	default:
+		return false
+	}
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, using \ as the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containsPattern returns a LIKE pattern with ESCAPE '\' matching strings containing s literally
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// foldCase maps every letter of s to the same rune as all letters differing
+// only in case, so that comparing folded strings ignores case
+func foldCase(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+// foldRune returns the smallest rune that is equivalent to r under Unicode simple case folding
+func foldRune(r rune) rune {
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < folded {
+			folded = f
+		}
+	}
+	return folded
+}
+
+// isASCII reports whether s consists of ASCII characters only
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// keywordCondition returns a condition matching commands containing keyword in case mode
+func (q *HistoryQuery) keywordCondition(keyword string, mode CaseMode) (string, []interface{}) {
+	return q.manager.backend.ContainsCondition(keyword, mode.Sensitive(keyword))
+}
@@ -0,0 +1,40 @@
+package history
+
+import "testing"
+
+func TestParseCaseMode(t *testing.T) {
+	for _, mode := range []CaseMode{CaseInsensitive, CaseSensitive, CaseSmart} {
+		parsed, err := ParseCaseMode(mode.String())
+		if err != nil || parsed != mode {
+			t.Errorf("ParseCaseMode(%q) = %v, %v", mode.String(), parsed, err)
+		}
+	}
+	if mode, err := ParseCaseMode(""); err != nil || mode != CaseInsensitive {
+		t.Errorf("expected empty name to select insensitive, got %v, %v", mode, err)
+	}
+	if _, err := ParseCaseMode("upper"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+	if CaseSmart.Next() != CaseInsensitive {
+		t.Errorf("expected modes to cycle, got %v", CaseSmart.Next())
+	}
+}
+
+func TestFoldCase(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"ÄPFEL", "äpfel"},
+		{"Straße", "STRAßE"},
+		{"K", "k"}, // Kelvin sign
+		{"ΣΊΣΥΦΟΣ", "σίσυφος"},
+	}
+	for _, tt := range tests {
+		if foldCase(tt.a) != foldCase(tt.b) {
+			t.Errorf("expected %q and %q to fold to the same string, got %q and %q", tt.a, tt.b, foldCase(tt.a), foldCase(tt.b))
+		}
+	}
+	if foldCase("a_b%") != "A_B%" {
+		t.Errorf("unexpected folding of ASCII: %q", foldCase("a_b%"))
+	}
+}
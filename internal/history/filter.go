@@ -0,0 +1,281 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is a parsed structured search expression.
+//
+// An expression is a list of terms that must all match. Groups of terms are
+// combined with OR, which binds weaker than the implicit AND:
+//
+//	make test OR go test dir:~/src -host:build01
+//
+// A term is a keyword, a "quoted phrase", or a field filter:
+//
+//	dir:PATH       run in PATH or a subdirectory (~ is expanded, relative paths are resolved)
+//	host:NAME      run on host NAME
+//	user:NAME      run by user NAME
+//	session:SID    run in shell session SID
+//	after:TIME     run at or after TIME
+//	before:TIME    run before TIME
+//
+// TIME is a date (2024-01-31), a date and time (2024-01-31T15:04), an RFC 3339
+// timestamp, today, yesterday, or a duration ago such as 30m, 12h, 7d or 2w.
+// A term prefixed with - must not match.
+type Filter struct {
+	// Case is how keywords match the case of commands
+	Case CaseMode
+
+	groups [][]filterTerm
+}
+
+// Keywords returns the keywords and phrases of f that are not negated, which
+// are contained in the matching commands
+func (f *Filter) Keywords() []string {
+	var keywords []string
+	for _, group := range f.groups {
+		for _, term := range group {
+			if term.field == "" && !term.negate {
+				keywords = append(keywords, term.value)
+			}
+		}
+	}
+	return keywords
+}
+
+// filterTerm is a single keyword or field filter
+type filterTerm struct {
+	negate bool
+	field  string
+	value  string
+	time   time.Time
+}
+
+// filterFields are the field names understood in field filters
+var filterFields = map[string]bool{
+	"dir":     true,
+	"host":    true,
+	"user":    true,
+	"session": true,
+	"after":   true,
+	"before":  true,
+	"exit":    true,
+}
+
+// relativeTimeRegex matches a duration ago such as 7d
+var relativeTimeRegex = regexp.MustCompile(`^(\d+)([mhdw])$`)
+
+// ParseFilter parses a structured search expression. Times are relative to now
+// in its location, and relative dir: paths are resolved against dir.
+func ParseFilter(input string, now time.Time, dir string) (*Filter, error) {
+	f := &Filter{}
+	var group []filterTerm
+	for _, token := range tokenizeFilter(input) {
+		if token.or {
+			if len(group) > 0 {
+				f.groups = append(f.groups, group)
+			}
+			group = nil
+			continue
+		}
+
+		term, err := parseFilterTerm(token, now, dir)
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, term)
+	}
+	if len(group) > 0 {
+		f.groups = append(f.groups, group)
+	}
+	return f, nil
+}
+
+// filterToken is a whitespace-separated part of an expression
+type filterToken struct {
+	negate bool
+	field  string
+	value  string
+	or     bool
+}
+
+// tokenizeFilter splits an expression into tokens. Double quotes group
+// whitespace into a value and keep "-", ":" and OR from being interpreted.
+func tokenizeFilter(input string) []filterToken {
+	var tokens []filterToken
+	var current strings.Builder
+	var token filterToken
+	inQuotes, quoted, started := false, false, false
+
+	flush := func() {
+		if !started {
+			return
+		}
+		token.value = current.String()
+		switch {
+		case !quoted && token.field == "" && !token.negate && token.value == "OR":
+			token = filterToken{or: true}
+		case !quoted && token.field == "" && token.negate && token.value == "":
+			// A lone "-" is a keyword
+			token = filterToken{value: "-"}
+		}
+		if token.or || token.field != "" || token.value != "" || quoted {
+			tokens = append(tokens, token)
+		}
+		current.Reset()
+		token = filterToken{}
+		inQuotes, quoted, started = false, false, false
+	}
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted, started = true, true
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			flush()
+		case !inQuotes && r == '-' && !started:
+			token.negate, started = true, true
+		case !inQuotes && r == ':' && !quoted && token.field == "" && filterFields[current.String()]:
+			token.field = current.String()
+			current.Reset()
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	flush()
+	return tokens
+}
+
+// parseFilterTerm validates a token and resolves its value
+func parseFilterTerm(token filterToken, now time.Time, dir string) (filterTerm, error) {
+	term := filterTerm{negate: token.negate, field: token.field, value: token.value}
+	if term.field != "" && term.value == "" {
+		return term, fmt.Errorf("%s: needs a value", term.field)
+	}
+
+	switch term.field {
+	case "exit":
+		return term, errors.New("exit: is not supported because exit statuses are not recorded")
+	case "dir":
+		path, err := ResolveDir(term.value, dir)
+		if err != nil {
+			return term, fmt.Errorf("dir: %w", err)
+		}
+		term.value = path
+	case "after", "before":
+		t, err := ParseTime(term.value, now)
+		if err != nil {
+			return term, fmt.Errorf("%s: %w", term.field, err)
+		}
+		term.time = t
+	}
+	return term, nil
+}
+
+// ResolveDir expands ~ and makes path absolute relative to dir
+func ResolveDir(path string, dir string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// ParseTime parses a time as accepted by after: and before:, relative to now in its location
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch value {
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+
+	if m := relativeTimeRegex.FindStringSubmatch(value); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid duration %q", value)
+		}
+		switch m[2] {
+		case "m":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "h":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		default:
+			return now.AddDate(0, 0, -7*n), nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected e.g. 2024-01-31, 2024-01-31T15:04, yesterday or 7d)", value)
+}
+
+// Match adds a condition to filter entries matching f
+func (q *HistoryQuery) Match(f *Filter) *HistoryQuery {
+	var groups []string
+	var args []interface{}
+	for _, group := range f.groups {
+		conditions := make([]string, len(group))
+		for i, term := range group {
+			condition, termArgs := q.termCondition(f, term)
+			if term.negate {
+				// NULL columns of old entries count as not matching
+				condition = "NOT COALESCE(" + condition + ", FALSE)"
+			}
+			conditions[i] = condition
+			args = append(args, termArgs...)
+		}
+		groups = append(groups, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	if len(groups) > 0 {
+		q.conditions = append(q.conditions, "("+strings.Join(groups, " OR ")+")")
+		q.args = append(q.args, args...)
+	}
+	return q
+}
+
+// termCondition returns the condition of a single term without its negation
+func (q *HistoryQuery) termCondition(f *Filter, term filterTerm) (string, []interface{}) {
+	switch term.field {
+	case "dir":
+		if condition, args := underDirectoryCondition(term.value); condition != "" {
+			return condition, args
+		}
+		return "TRUE", nil
+	case "host":
+		return "executing_host = ?", []interface{}{term.value}
+	case "user":
+		return "executing_user = ?", []interface{}{term.value}
+	case "session":
+		return "sid = ?", []interface{}{term.value}
+	case "after":
+		return q.manager.backend.TimeCondition(">=", term.time)
+	case "before":
+		return q.manager.backend.TimeCondition("<", term.time)
+	default:
+		return q.keywordCondition(term.value, f.Case)
+	}
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 30, 0, 0, time.UTC)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home directory: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected [][]filterTerm
+	}{
+		{"", nil},
+		{"git status", [][]filterTerm{{{value: "git"}, {value: "status"}}}},
+		{`"git status" -push`, [][]filterTerm{{{value: "git status"}, {negate: true, value: "push"}}}},
+		{`"-v" "OR" "dir:x"`, [][]filterTerm{{{value: "-v"}, {value: "OR"}, {value: "dir:x"}}}},
+		{"ls - http://x", [][]filterTerm{{{value: "ls"}, {value: "-"}, {value: "http://x"}}}},
+		{`host:"my host" user:me session:s1`, [][]filterTerm{{
+			{field: "host", value: "my host"},
+			{field: "user", value: "me"},
+			{field: "session", value: "s1"},
+		}}},
+		{"dir:app -dir:/tmp/ dir:~/src", [][]filterTerm{{
+			{field: "dir", value: "/src/app"},
+			{negate: true, field: "dir", value: "/tmp"},
+			{field: "dir", value: filepath.Join(home, "src")},
+		}}},
+		{"make OR OR go test OR", [][]filterTerm{{{value: "make"}}, {{value: "go"}, {value: "test"}}}},
+		{"after:2024-01-31 before:yesterday", [][]filterTerm{{
+			{field: "after", value: "2024-01-31", time: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+			{field: "before", value: "yesterday", time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		}}},
+		{"after:today after:2024-01-31T15:04 after:90m after:2w", [][]filterTerm{{
+			{field: "after", value: "today", time: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+			{field: "after", value: "2024-01-31T15:04", time: time.Date(2024, 1, 31, 15, 4, 0, 0, time.UTC)},
+			{field: "after", value: "90m", time: time.Date(2024, 5, 2, 11, 0, 0, 0, time.UTC)},
+			{field: "after", value: "2w", time: time.Date(2024, 4, 18, 12, 30, 0, 0, time.UTC)},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			f, err := ParseFilter(tt.input, now, "/src")
+			if err != nil {
+				t.Fatalf("ParseFilter failed: %v", err)
+			}
+			if !reflect.DeepEqual(f.groups, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, f.groups)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"host:", "host: needs a value"},
+		{"after:soon", `after: invalid time "soon"`},
+		{"before:2024-13-01", `before: invalid time "2024-13-01"`},
+		{"exit:0", "exit statuses are not recorded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseFilter(tt.input, time.Now(), "/src")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestFilterKeywords(t *testing.T) {
+	f, err := ParseFilter(`git "log -p" -push host:ci OR make`, time.Now(), "/src")
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	expected := []string{"git", "log -p", "make"}
+	if got := f.Keywords(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
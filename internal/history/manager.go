@@ -1,15 +1,16 @@
 package history
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"os"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/sett4/duckhist/internal/migrate"
-
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/oklog/ulid/v2"
 )
 
@@ -24,8 +25,21 @@ type Entry struct {
 	SID       string
 }
 
+// Session summarizes the commands recorded in one shell session
+type Session struct {
+	SID       string
+	Count     int
+	Start     time.Time
+	End       time.Time
+	Directory string // directory of the first command
+	Hostname  string
+}
+
 type Manager struct {
-	db *sql.DB
+	db      *sql.DB
+	backend Backend
+	// dedupSession makes the session part of the context of duplicates
+	dedupSession bool
 }
 
 type HistoryQuery struct {
@@ -33,7 +47,10 @@ type HistoryQuery struct {
 	conditions []string
 	args       []interface{}
 	orderBy    string
+	orderArgs  []interface{}
 	limit      *int
+	offset     int
+	distinct   bool
 }
 
 // Query creates a new HistoryQuery for building database queries
@@ -58,8 +75,107 @@ func (q *HistoryQuery) NotInDirectory(dir string) *HistoryQuery {
 	return q
 }
 
-// Search adds a condition to filter entries containing the search term
+// UnderDirectory adds a condition to filter entries in the specified directory or any of its subdirectories
+func (q *HistoryQuery) UnderDirectory(dir string) *HistoryQuery {
+	if condition, args := underDirectoryCondition(dir); condition != "" {
+		q.conditions = append(q.conditions, condition)
+		q.args = append(q.args, args...)
+	}
+	return q
+}
+
+// underDirectoryCondition returns a condition matching entries in dir or any of
+// its subdirectories, or an empty condition if every entry matches
+func underDirectoryCondition(dir string) (string, []interface{}) {
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" {
+		// Every absolute path is under the root directory
+		return "", nil
+	}
+	prefix := dir + "/"
+	return "(executing_dir = ? OR substr(executing_dir, 1, ?) = ?)", []interface{}{dir, utf8.RuneCountInString(prefix), prefix}
+}
+
+// OnHost adds a condition to filter entries executed on the specified host
+func (q *HistoryQuery) OnHost(host string) *HistoryQuery {
+	q.conditions = append(q.conditions, "executing_host = ?")
+	q.args = append(q.args, host)
+	return q
+}
+
+// InSession adds a condition to filter entries recorded in the specified shell session
+func (q *HistoryQuery) InSession(sid string) *HistoryQuery {
+	q.conditions = append(q.conditions, "sid = ?")
+	q.args = append(q.args, sid)
+	return q
+}
+
+// After adds a condition to filter entries recorded after the entry with the specified ID.
+// IDs are ULIDs, so they sort in the order the entries were recorded.
+func (q *HistoryQuery) After(id string) *HistoryQuery {
+	q.conditions = append(q.conditions, "id > ?")
+	q.args = append(q.args, id)
+	return q
+}
+
+// Since adds a condition to filter entries executed at or after t
+func (q *HistoryQuery) Since(t time.Time) *HistoryQuery {
+	condition, args := q.manager.backend.TimeCondition(">=", t)
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// Until adds a condition to filter entries executed before t
+func (q *HistoryQuery) Until(t time.Time) *HistoryQuery {
+	condition, args := q.manager.backend.TimeCondition("<", t)
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// OnTTY adds a condition to filter entries recorded on the specified terminal
+func (q *HistoryQuery) OnTTY(tty string) *HistoryQuery {
+	q.conditions = append(q.conditions, "tty = ?")
+	q.args = append(q.args, tty)
+	return q
+}
+
+// WithCommand adds a condition to filter entries of exactly the specified command
+func (q *HistoryQuery) WithCommand(command string) *HistoryQuery {
+	q.conditions = append(q.conditions, "command = ?")
+	q.args = append(q.args, command)
+	return q
+}
+
+// WithPrefix adds a condition to filter entries whose command starts with prefix.
+// The comparison is case-sensitive and can use the index on the command column.
+func (q *HistoryQuery) WithPrefix(prefix string) *HistoryQuery {
+	if prefix == "" {
+		return q
+	}
+	condition, args := q.manager.backend.PrefixCondition(prefix)
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// Regexp adds a condition to filter entries whose command contains a match of re
+func (q *HistoryQuery) Regexp(re *regexp.Regexp) *HistoryQuery {
+	condition, args := q.manager.backend.RegexpCondition(re.String())
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// Search adds a condition to filter entries containing the search term, ignoring case
 func (q *HistoryQuery) Search(term string) *HistoryQuery {
+	return q.SearchWithCase(term, CaseInsensitive)
+}
+
+// SearchWithCase adds a condition to filter entries containing the search term,
+// matching case according to mode
+func (q *HistoryQuery) SearchWithCase(term string, mode CaseMode) *HistoryQuery {
 	term = strings.TrimSpace(term)
 	if term == "" {
 		return q
@@ -68,10 +184,11 @@ func (q *HistoryQuery) Search(term string) *HistoryQuery {
 	// Parse keywords and quoted phrases
 	keywords := parseSearchTerms(term)
 	
-	// Add LIKE condition for each keyword/phrase (AND logic)
+	// Add a condition for each keyword/phrase (AND logic)
 	for _, keyword := range keywords {
-		q.conditions = append(q.conditions, "command LIKE ?")
-		q.args = append(q.args, fmt.Sprintf("%%%s%%", keyword))
+		condition, args := q.keywordCondition(keyword, mode)
+		q.conditions = append(q.conditions, condition)
+		q.args = append(q.args, args...)
 	}
 	
 	return q
@@ -131,31 +248,116 @@ func (q *HistoryQuery) Limit(n int) *HistoryQuery {
 	return q
 }
 
+// Offset sets the number of matching entries to skip before returning entries
+func (q *HistoryQuery) Offset(n int) *HistoryQuery {
+	q.offset = n
+	return q
+}
+
 // OrderByCurrentDirFirst sets the order to prioritize entries from the specified directory
 func (q *HistoryQuery) OrderByCurrentDirFirst(dir string) *HistoryQuery {
 	q.orderBy = "CASE WHEN executing_dir = ? THEN 0 ELSE 1 END, id DESC"
-	q.args = append(q.args, dir)
+	q.orderArgs = []interface{}{dir}
+	return q
+}
+
+// DistinctCommands returns every command only once, as its first entry in the
+// order of the query, e.g. its latest entry by default
+func (q *HistoryQuery) DistinctCommands() *HistoryQuery {
+	q.distinct = true
+	return q
+}
+
+// OrderByOldestFirst sets the order to return entries in the order they were recorded
+func (q *HistoryQuery) OrderByOldestFirst() *HistoryQuery {
+	q.orderBy = "id ASC"
+	q.orderArgs = nil
 	return q
 }
 
+// where returns the WHERE clause of the query, or an empty string without conditions
+func (q *HistoryQuery) where() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// Count returns the number of matching entries, ignoring limit and offset
+func (q *HistoryQuery) Count() (int, error) {
+	return q.CountContext(context.Background())
+}
+
+// CountContext is like Count but stops when ctx is canceled
+func (q *HistoryQuery) CountContext(ctx context.Context) (int, error) {
+	count := "COUNT(*)"
+	if q.distinct {
+		count = "COUNT(DISTINCT command)"
+	}
+	var n int
+	err := q.manager.db.QueryRowContext(ctx, "SELECT "+count+" FROM history"+q.where(), q.args...).Scan(&n)
+	return n, err
+}
+
 // GetEntries executes the query and returns the matching entries
 func (q *HistoryQuery) GetEntries() ([]Entry, error) {
-	query := "SELECT id, command, executed_at, executing_host, executing_dir, executing_user, tty, sid FROM history"
+	return q.GetEntriesContext(context.Background())
+}
 
-	if len(q.conditions) > 0 {
-		query += " WHERE " + strings.Join(q.conditions, " AND ")
+// GetEntriesContext is like GetEntries but stops when ctx is canceled
+func (q *HistoryQuery) GetEntriesContext(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	err := q.EachContext(ctx, func(entry Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return entries, nil
+}
 
-	query += " ORDER BY " + q.orderBy
+// Each executes the query and calls fn for every matching entry as it is read,
+// without collecting them in memory. Iteration stops at the first error
+// returned by fn, which is then returned by Each.
+func (q *HistoryQuery) Each(fn func(Entry) error) error {
+	return q.EachContext(context.Background(), fn)
+}
 
+// EachContext is like Each but stops when ctx is canceled
+func (q *HistoryQuery) EachContext(ctx context.Context, fn func(Entry) error) error {
+	// tty and sid are NULL for entries recorded before they were added to the schema
+	query := "SELECT id, command, executed_at, executing_host, executing_dir, executing_user, COALESCE(tty, ''), COALESCE(sid, '') FROM history"
+
+	// Condition arguments come before the ones of the ORDER BY clause
+	var args []interface{}
+	if q.distinct {
+		// Number the entries of every command in the order of the query and keep
+		// the first ones, so the database does the deduplication
+		query = "SELECT id, command, executed_at, executing_host, executing_dir, executing_user, COALESCE(tty, ''), COALESCE(sid, '') FROM (" +
+			"SELECT *, ROW_NUMBER() OVER (PARTITION BY command ORDER BY " + q.orderBy + ") AS command_rank FROM history" + q.where() +
+			") AS h WHERE command_rank = 1"
+		args = append(append(args, q.orderArgs...), q.args...)
+	} else {
+		query += q.where()
+		args = append(args, q.args...)
+	}
+	query += " ORDER BY " + q.orderBy
+	args = append(args, q.orderArgs...)
 	if q.limit != nil {
 		query += " LIMIT ?"
-		q.args = append(q.args, *q.limit)
+		args = append(args, *q.limit)
+	} else if q.offset > 0 {
+		query += q.manager.backend.NoLimit()
+	}
+	if q.offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, q.offset)
 	}
 
-	rows, err := q.manager.db.Query(query, q.args...)
+	rows, err := q.manager.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
@@ -163,85 +365,185 @@ func (q *HistoryQuery) GetEntries() ([]Entry, error) {
 		}
 	}()
 
-	var entries []Entry
 	for rows.Next() {
 		var entry Entry
 		err := rows.Scan(&entry.ID, &entry.Command, &entry.Timestamp, &entry.Hostname, &entry.Directory, &entry.Username, &entry.TTY, &entry.SID)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		entries = append(entries, entry)
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Option configures how a Manager opens the database
+type Option func(*options)
+
+type options struct {
+	autoMigrate     bool
+	backend         string
+	skipSchemaCheck bool
+	dedupSession    bool
+}
+
+// WithAutoMigrate applies pending schema migrations when the database is opened
+// instead of only warning about them
+func WithAutoMigrate(enabled bool) Option {
+	return func(o *options) {
+		o.autoMigrate = enabled
+	}
+}
+
+// WithBackend selects the storage backend by name (default is DefaultBackend)
+func WithBackend(name string) Option {
+	return func(o *options) {
+		o.backend = name
+	}
+}
+
+// WithSessionDedup only skips a command as a duplicate if it was recorded in the
+// same session, so that every session keeps a complete timeline
+func WithSessionDedup(enabled bool) Option {
+	return func(o *options) {
+		o.dedupSession = enabled
 	}
+}
+
+// WithoutSchemaCheck opens the database without checking the schema version and
+// without auto migration. Commands run on every keystroke use it to save the
+// query; an outdated schema then surfaces as a query error.
+func WithoutSchemaCheck() Option {
+	return func(o *options) {
+		o.skipSchemaCheck = true
+	}
+}
 
-	return entries, rows.Err()
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
-// checkSchemaVersion checks if the database schema version matches the required version
-// and prints a warning if they don't match
-func checkSchemaVersion(db *sql.DB) {
-	ok, current, required, err := migrate.CheckSchemaVersion(db)
+// checkSchemaVersion reports whether the schema is up to date.
+// Without auto migration, a mismatch is only reported as a warning.
+func checkSchemaVersion(backend Backend, db *sql.DB, warn bool) bool {
+	ok, current, required, err := backend.CheckSchema(db)
 	if err != nil {
-		// Just log the error and continue, don't prevent operation
-		fmt.Fprintf(os.Stderr, "Warning: Failed to check schema version: %v\n", err)
-		return
+		if warn {
+			// Just log the error and continue, don't prevent operation
+			fmt.Fprintf(os.Stderr, "Warning: Failed to check schema version: %v\n", err)
+		}
+		return false
 	}
 
-	if !ok {
+	if !ok && warn {
 		fmt.Fprintf(os.Stderr, "Warning: Database schema version mismatch. Current: %d, Required: %d\n", current, required)
 		fmt.Fprintf(os.Stderr, "Please run 'duckhist schema-migrate' to update the schema\n")
 	}
+	return ok
 }
 
-// NewManagerReadWrite creates a new Manager with read-write access to the database
-func NewManagerReadWrite(dbPath string) (*Manager, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// newManager opens the database at dbPath with the configured backend.
+// With auto migration, a missing or outdated database is migrated before it is
+// opened again, so that the backend never migrates a database this process has open.
+func newManager(dbPath string, readOnly bool, opts []Option) (*Manager, error) {
+	o := newOptions(opts)
+	backend, err := LookupBackend(o.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	// Enable foreign key constraints and WAL mode
-	if _, err := db.Exec("PRAGMA foreign_keys = ON; PRAGMA journal_mode = WAL;"); err != nil {
-		if closeErr := db.Close(); closeErr != nil {
-			return nil, fmt.Errorf("failed to enable PRAGMA and close DB: %v, close error: %v", err, closeErr)
+	db, err := backend.Open(dbPath, readOnly)
+	if o.skipSchemaCheck {
+		if err != nil {
+			return nil, err
 		}
-		return nil, err
+		return &Manager{db: db, backend: backend, dedupSession: o.dedupSession}, nil
+	}
+	if !o.autoMigrate {
+		if err != nil {
+			return nil, err
+		}
+		checkSchemaVersion(backend, db, true)
+		return &Manager{db: db, backend: backend, dedupSession: o.dedupSession}, nil
 	}
 
-	// Check schema version
-	checkSchemaVersion(db)
-
-	return &Manager{db: db}, nil
-}
-
-// NewManagerReadOnly creates a new Manager with read-only access to the database
-func NewManagerReadOnly(dbPath string) (*Manager, error) {
-	db, err := sql.Open("sqlite3", dbPath+"?mode=ro")
+	if err == nil {
+		if checkSchemaVersion(backend, db, false) {
+			return &Manager{db: db, backend: backend, dedupSession: o.dedupSession}, nil
+		}
+		if err := db.Close(); err != nil {
+			log.Printf("failed to close database: %v", err)
+		}
+	}
+	if err := backend.Migrate(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	db, err = backend.Open(dbPath, readOnly)
 	if err != nil {
 		return nil, err
 	}
+	return &Manager{db: db, backend: backend, dedupSession: o.dedupSession}, nil
+}
 
-	// Check schema version
-	checkSchemaVersion(db)
+// NewManagerReadWrite creates a new Manager with read-write access to the database
+func NewManagerReadWrite(dbPath string, opts ...Option) (*Manager, error) {
+	return newManager(dbPath, false, opts)
+}
 
-	manager := &Manager{db: db}
-	return manager, nil
+// NewManagerReadOnly creates a new Manager with read-only access to the database.
+// With WithAutoMigrate, pending migrations are applied through a separate
+// read-write connection before reading.
+func NewManagerReadOnly(dbPath string, opts ...Option) (*Manager, error) {
+	return newManager(dbPath, true, opts)
 }
 
 func (m *Manager) Close() error {
 	return m.db.Close()
 }
 
-// isDuplicate checks if the command already exists in the same context
-func (m *Manager) isDuplicate(command string, directory string, hostname string, username string) (bool, error) {
-	var count int
-	err := m.db.QueryRow(`
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// CommandRecord holds a command to be added to history with its context
+type CommandRecord struct {
+	Command    string
+	Directory  string
+	TTY        string
+	SID        string
+	Hostname   string
+	Username   string
+	ExecutedAt time.Time
+	NoDedup    bool
+}
+
+// isDuplicate checks if the command already exists in the same context.
+// With perSession, the session is part of the context, so every session keeps a complete timeline.
+func isDuplicate(ctx context.Context, db execer, command string, directory string, hostname string, username string, sid string, perSession bool) (bool, error) {
+	query := `
 		SELECT COUNT(*)
 		FROM history
 		WHERE command = ?
 		AND executing_dir = ?
 		AND executing_host = ?
-		AND executing_user = ?`,
-		command, directory, hostname, username).Scan(&count)
+		AND executing_user = ?`
+	args := []interface{}{command, directory, hostname, username}
+	if perSession {
+		query += `
+		AND COALESCE(sid, '') = ?`
+		args = append(args, sid)
+	}
+
+	var count int
+	err := db.QueryRowContext(ctx, query, args...).Scan(&count)
 
 	if err != nil {
 		return false, fmt.Errorf("failed to check for duplicate: %w", err)
@@ -250,22 +552,19 @@ func (m *Manager) isDuplicate(command string, directory string, hostname string,
 	return count > 0, nil
 }
 
-// AddCommand adds a command to history with a specific timestamp
-func (m *Manager) AddCommand(command string, directory string, tty string, sid string, hostname string, username string, executedAt time.Time, noDedup bool) (bool, error) {
-	if directory == "" {
+// addCommand inserts a record unless it is a duplicate and deduplication is enabled
+func addCommand(ctx context.Context, db execer, r CommandRecord, perSession bool) (bool, error) {
+	if r.Directory == "" {
 		var err error
-		directory, err = os.Getwd()
+		r.Directory, err = os.Getwd()
 		if err != nil {
 			return false, fmt.Errorf("failed to get current directory: %w", err)
 		}
 	}
 
-	var isDup bool
-	var err error
-
-	if !noDedup {
+	if !r.NoDedup {
 		// Check for duplicates
-		isDup, err = m.isDuplicate(command, directory, hostname, username)
+		isDup, err := isDuplicate(ctx, db, r.Command, r.Directory, r.Hostname, r.Username, r.SID, perSession)
 		if err != nil {
 			return false, err
 		}
@@ -277,15 +576,104 @@ func (m *Manager) AddCommand(command string, directory string, tty string, sid s
 
 	id := ulid.Make().String()
 
-	_, err = m.db.Exec(`
+	_, err := db.ExecContext(ctx, `
         INSERT INTO history (
             id, command, executed_at, executing_host, 
             executing_dir, executing_user, tty, sid
         ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		id, command, executedAt, hostname, directory, username, tty, sid)
+		id, r.Command, r.ExecutedAt, r.Hostname, r.Directory, r.Username, r.TTY, r.SID)
 	return false, err
 }
 
+// AddCommand adds a command to history with a specific timestamp
+func (m *Manager) AddCommand(command string, directory string, tty string, sid string, hostname string, username string, executedAt time.Time, noDedup bool) (bool, error) {
+	return m.AddCommandContext(context.Background(), command, directory, tty, sid, hostname, username, executedAt, noDedup)
+}
+
+// AddCommandContext is like AddCommand but stops when ctx is canceled
+func (m *Manager) AddCommandContext(ctx context.Context, command string, directory string, tty string, sid string, hostname string, username string, executedAt time.Time, noDedup bool) (bool, error) {
+	return addCommand(ctx, m.db, CommandRecord{
+		Command:    command,
+		Directory:  directory,
+		TTY:        tty,
+		SID:        sid,
+		Hostname:   hostname,
+		Username:   username,
+		ExecutedAt: executedAt,
+		NoDedup:    noDedup,
+	}, m.dedupSession)
+}
+
+// AddCommands adds several commands in a single transaction.
+// It returns whether each record was skipped as a duplicate. Records are checked
+// for duplicates in order, so a batch may contain the same command twice.
+func (m *Manager) AddCommands(records []CommandRecord) ([]bool, error) {
+	return m.AddCommandsContext(context.Background(), records)
+}
+
+// AddCommandsContext is like AddCommands but rolls back when ctx is canceled
+func (m *Manager) AddCommandsContext(ctx context.Context, records []CommandRecord) ([]bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dups := make([]bool, len(records))
+	for i, r := range records {
+		dups[i], err = addCommand(ctx, tx, r, m.dedupSession)
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return nil, fmt.Errorf("failed to add command: %v, rollback failed: %v", err, rbErr)
+			}
+			return nil, err
+		}
+	}
+
+	return dups, tx.Commit()
+}
+
+// deleteBatchSize is the number of entries deleted by one statement, keeping
+// the number of placeholders below the limits of the database engines
+const deleteBatchSize = 500
+
+// DeleteEntries deletes the entries with the given IDs in a single transaction
+// and returns the number of entries deleted. Unknown IDs are ignored.
+func (m *Manager) DeleteEntries(ids []string) (int64, error) {
+	return m.DeleteEntriesContext(context.Background(), ids)
+}
+
+// DeleteEntriesContext is like DeleteEntries but rolls back when ctx is canceled
+func (m *Manager) DeleteEntriesContext(ctx context.Context, ids []string) (int64, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		batch := ids[start:min(start+deleteBatchSize, len(ids))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		result, err := tx.ExecContext(ctx, "DELETE FROM history WHERE id IN ("+placeholders+")", args...)
+		if err == nil {
+			var n int64
+			n, err = result.RowsAffected()
+			deleted += n
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return 0, fmt.Errorf("failed to delete entries: %v, rollback failed: %v", err, rbErr)
+			}
+			return 0, err
+		}
+	}
+
+	return deleted, tx.Commit()
+}
+
 func (m *Manager) ListCommands() ([]string, error) {
 	entries, err := m.Query().GetEntries()
 	if err != nil {
@@ -300,26 +688,73 @@ func (m *Manager) ListCommands() ([]string, error) {
 	return commands, nil
 }
 
+// ListSessions returns the most recent shell sessions, newest first.
+// Entries without a session ID are not part of any session.
+func (m *Manager) ListSessions(limit int) ([]Session, error) {
+	rows, err := m.db.Query(`
+		WITH sessions AS (
+			SELECT sid, COUNT(*) AS commands, MIN(id) AS first_id, MAX(id) AS last_id
+			FROM history
+			WHERE sid IS NOT NULL AND sid != ''
+			GROUP BY sid
+		)
+		SELECT s.sid, s.commands, f.executed_at, l.executed_at, f.executing_dir, f.executing_host
+		FROM sessions s
+		JOIN history f ON f.id = s.first_id
+		JOIN history l ON l.id = s.last_id
+		ORDER BY s.last_id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close rows: %v\n", err)
+		}
+	}()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.SID, &s.Count, &s.Start, &s.End, &s.Directory, &s.Hostname); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
 // FindHistory retrieves commands with current directory entries first
 // If limit is provided, returns only that many entries
 func (m *Manager) FindHistory(currentDir string, limit *int) ([]Entry, error) {
+	return m.FindHistoryContext(context.Background(), currentDir, limit)
+}
+
+// FindHistoryContext is like FindHistory but stops when ctx is canceled
+func (m *Manager) FindHistoryContext(ctx context.Context, currentDir string, limit *int) ([]Entry, error) {
 	q := m.Query().OrderByCurrentDirFirst(currentDir)
 	if limit != nil {
 		q.Limit(*limit)
 	}
-	return q.GetEntries()
+	return q.GetEntriesContext(ctx)
 }
 
 // FindByCommand searches for commands matching the given query
 // If query is empty, returns all commands
 // Results are ordered with current directory entries first
 func (m *Manager) FindByCommand(query string, currentDir string) ([]Entry, error) {
+	return m.FindByCommandContext(context.Background(), query, currentDir)
+}
+
+// FindByCommandContext is like FindByCommand but stops when ctx is canceled
+func (m *Manager) FindByCommandContext(ctx context.Context, query string, currentDir string) ([]Entry, error) {
 	if query == "" {
-		return m.FindHistory(currentDir, nil)
+		return m.FindHistoryContext(ctx, currentDir, nil)
 	}
 
 	return m.Query().
 		Search(query).
 		OrderByCurrentDirFirst(currentDir).
-		GetEntries()
+		GetEntriesContext(ctx)
 }
@@ -2,6 +2,7 @@ package history
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
@@ -9,6 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/sett4/duckhist/internal/migrate"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -230,3 +234,223 @@ func TestParseSearchTerms(t *testing.T) {
 		})
 	}
 }
+
+func TestHistoryQueryCount(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+	if _, _, err := migrate.Up(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	manager, err := NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	for i, dir := range []string{"/a", "/b", "/a", "/b", "/a"} {
+		if _, err := manager.AddCommand(fmt.Sprintf("echo %d", i), dir, "", "", "localhost", "testuser", time.Now(), true); err != nil {
+			t.Fatalf("failed to add command: %v", err)
+		}
+	}
+
+	// Conditions added after the ordering must still bind to the right placeholders
+	q := manager.Query().OrderByCurrentDirFirst("/b").InDirectory("/a")
+	entries, err := q.GetEntries()
+	if err != nil {
+		t.Fatalf("GetEntries failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 3 entries, got %+v", entries)
+	}
+
+	count, err := manager.Query().Search("echo").Limit(1).Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("expected count 5, got %d", count)
+	}
+
+	entries, err = manager.Query().OrderByCurrentDirFirst("/b").Offset(1).Limit(2).GetEntries()
+	if err != nil {
+		t.Fatalf("GetEntries failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Command != "echo 1" || entries[1].Command != "echo 4" {
+		t.Errorf("unexpected page: %+v", entries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := manager.Query().GetEntriesContext(ctx); err == nil {
+		t.Error("expected error for canceled context")
+	}
+}
+
+func TestManagerContext(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+	if _, _, err := migrate.Up(dbPath); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	manager, err := NewManagerReadWrite(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := manager.AddCommandContext(ctx, fmt.Sprintf("echo %d", i), "/tmp", "", "", "localhost", "testuser", time.Now(), false); err != nil {
+			t.Fatalf("AddCommandContext failed: %v", err)
+		}
+	}
+
+	t.Run("each", func(t *testing.T) {
+		var commands []string
+		err := manager.Query().Each(func(entry Entry) error {
+			commands = append(commands, entry.Command)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Each failed: %v", err)
+		}
+		if strings.Join(commands, ",") != "echo 2,echo 1,echo 0" {
+			t.Errorf("unexpected commands: %v", commands)
+		}
+	})
+
+	t.Run("each stops on error", func(t *testing.T) {
+		errStop := fmt.Errorf("stop")
+		calls := 0
+		err := manager.Query().Each(func(entry Entry) error {
+			calls++
+			return errStop
+		})
+		if err != errStop || calls != 1 {
+			t.Errorf("expected iteration to stop after 1 call, got %d calls and %v", calls, err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := manager.AddCommandContext(canceled, "ls", "/tmp", "", "", "localhost", "testuser", time.Now(), false); err == nil {
+			t.Error("expected AddCommandContext to fail")
+		}
+		if _, err := manager.AddCommandsContext(canceled, []CommandRecord{{Command: "ls", Directory: "/tmp"}}); err == nil {
+			t.Error("expected AddCommandsContext to fail")
+		}
+		if _, err := manager.FindByCommandContext(canceled, "echo", "/tmp"); err == nil {
+			t.Error("expected FindByCommandContext to fail")
+		}
+
+		entries, err := manager.FindHistoryContext(ctx, "/tmp", nil)
+		if err != nil {
+			t.Fatalf("FindHistoryContext failed: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Errorf("expected 3 entries, got %d", len(entries))
+		}
+	})
+}
+
+func TestDeleteEntries(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+	manager, err := NewManagerReadWrite(dbPath, WithAutoMigrate(true))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	// More entries than are deleted by one statement
+	records := make([]CommandRecord, deleteBatchSize+100)
+	for i := range records {
+		records[i] = CommandRecord{Command: fmt.Sprintf("echo %d", i), Directory: "/tmp", ExecutedAt: time.Now()}
+	}
+	if _, err := manager.AddCommands(records); err != nil {
+		t.Fatalf("AddCommands failed: %v", err)
+	}
+	entries, err := manager.Query().GetEntries()
+	if err != nil {
+		t.Fatalf("GetEntries failed: %v", err)
+	}
+
+	var ids []string
+	for _, entry := range entries[1:] {
+		ids = append(ids, entry.ID)
+	}
+	ids = append(ids, "unknown")
+	deleted, err := manager.DeleteEntries(ids)
+	if err != nil {
+		t.Fatalf("DeleteEntries failed: %v", err)
+	}
+	if deleted != int64(len(entries)-1) {
+		t.Errorf("expected %d entries to be deleted, got %d", len(entries)-1, deleted)
+	}
+
+	remaining, err := manager.Query().GetEntries()
+	if err != nil {
+		t.Fatalf("GetEntries failed: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != entries[0].ID {
+		t.Errorf("expected only the newest entry to remain, got %+v", remaining)
+	}
+}
+
+func TestWithoutSchemaCheck(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+	manager, err := NewManagerReadWrite(dbPath, WithAutoMigrate(true), WithoutSchemaCheck())
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("failed to close manager: %v", err)
+		}
+	}()
+
+	// Auto migration is skipped along with the check
+	if _, err := manager.Query().Limit(1).GetEntries(); err == nil {
+		t.Error("expected query on an unmigrated database to fail")
+	}
+}
+
+func TestSessionDedup(t *testing.T) {
+	for _, perSession := range []bool{false, true} {
+		t.Run(fmt.Sprint(perSession), func(t *testing.T) {
+			dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+			manager, err := NewManagerReadWrite(dbPath, WithAutoMigrate(true), WithSessionDedup(perSession))
+			if err != nil {
+				t.Fatalf("failed to create manager: %v", err)
+			}
+			defer func() {
+				if err := manager.Close(); err != nil {
+					t.Errorf("failed to close manager: %v", err)
+				}
+			}()
+
+			dups, err := manager.AddCommands([]CommandRecord{
+				{Command: "make", Directory: "/src", SID: "s1", ExecutedAt: time.Now()},
+				{Command: "make", Directory: "/src", SID: "s2", ExecutedAt: time.Now()},
+				{Command: "make", Directory: "/src", SID: "s2", ExecutedAt: time.Now()},
+			})
+			if err != nil {
+				t.Fatalf("AddCommands failed: %v", err)
+			}
+			// Another session only records the command again with per-session dedup
+			if dups[0] || dups[1] != !perSession || !dups[2] {
+				t.Errorf("unexpected duplicates: %v", dups)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package history
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// View is a predefined view available to queries run with QuerySQL
+type View struct {
+	Name        string
+	Description string
+	Query       string
+}
+
+// Views are the predefined views, written in SQL understood by every backend
+var Views = []View{
+	{
+		Name:        "commands",
+		Description: "distinct commands with the number of runs and the time of the last run",
+		Query: `SELECT command, COUNT(*) AS runs, MAX(executed_at) AS last_run
+			FROM history
+			GROUP BY command`,
+	},
+	{
+		Name:        "commands_by_dir",
+		Description: "distinct commands per directory with the number of runs and the time of the last run",
+		Query: `SELECT executing_dir AS directory, command, COUNT(*) AS runs, MAX(executed_at) AS last_run
+			FROM history
+			GROUP BY executing_dir, command`,
+	},
+	{
+		Name:        "sessions",
+		Description: "shell sessions with their host, first and last command time and number of commands",
+		Query: `SELECT sid, MIN(executing_host) AS host, MIN(executed_at) AS started_at, MAX(executed_at) AS ended_at, COUNT(*) AS commands
+			FROM history
+			WHERE sid IS NOT NULL AND sid != ''
+			GROUP BY sid`,
+	},
+}
+
+// QuerySQL runs an arbitrary SQL query on a dedicated connection where the
+// predefined Views exist as temporary views. It calls header with the column
+// names and then row for every result row as it is read.
+// On a Manager created with NewManagerReadOnly, the query cannot modify the database.
+func (m *Manager) QuerySQL(ctx context.Context, query string, header func([]string) error, row func([]interface{}) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// The connection carries the views and settings of this query, so it
+		// is discarded instead of being returned to the pool. This also closes conn.
+		if err := conn.Raw(func(interface{}) error { return driver.ErrBadConn }); !errors.Is(err, driver.ErrBadConn) {
+			log.Printf("failed to discard connection: %v", err)
+		}
+	}()
+
+	for _, view := range Views {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TEMP VIEW %s AS %s", view.Name, view.Query)); err != nil {
+			return fmt.Errorf("failed to create view %s: %w", view.Name, err)
+		}
+	}
+	if statement := m.backend.QueryOnly(); statement != "" {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to make connection read-only: %w", err)
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("failed to close rows: %v", err)
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if err := header(columns); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		if err := row(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
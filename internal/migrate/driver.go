@@ -26,6 +26,11 @@ func init() {
 	database.Register("sqlite3", &SQLite{})
 }
 
+// LatestSchemaVersion is the version of the newest embedded migration.
+// It is compiled in so that opening a database does not have to scan the
+// embedded migrations; update it when adding a migration.
+const LatestSchemaVersion = 4
+
 // GetLatestMigrationVersion returns the latest migration version from embedded migrations
 func GetLatestMigrationVersion() (int, error) {
 	migrationsFS := embedded.GetMigrationsFS()
@@ -69,15 +74,11 @@ func GetLatestMigrationVersion() (int, error) {
 
 // CheckSchemaVersion checks if the current database schema version matches the required version
 func CheckSchemaVersion(db *sql.DB) (bool, int, int, error) {
-	// Get the latest migration version
-	requiredVersion, err := GetLatestMigrationVersion()
-	if err != nil {
-		return false, 0, 0, fmt.Errorf("failed to get latest migration version: %w", err)
-	}
+	requiredVersion := LatestSchemaVersion
 
 	// Check if schema_migrations table exists
 	var tableExists bool
-	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type='table' AND name='schema_migrations')").Scan(&tableExists)
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type='table' AND name='schema_migrations')").Scan(&tableExists)
 	if err != nil {
 		return false, 0, requiredVersion, fmt.Errorf("failed to check if schema_migrations table exists: %w", err)
 	}
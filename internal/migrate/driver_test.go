@@ -155,3 +155,70 @@ func TestGetLatestMigrationVersion(t *testing.T) {
 		t.Errorf("expected version to be > 0, got %d", version)
 	}
 }
+
+func TestLatestSchemaVersion(t *testing.T) {
+	version, err := GetLatestMigrationVersion()
+	if err != nil {
+		t.Fatalf("GetLatestMigrationVersion failed: %v", err)
+	}
+	if LatestSchemaVersion != version {
+		t.Errorf("LatestSchemaVersion is %d but the newest embedded migration is %d", LatestSchemaVersion, version)
+	}
+}
+
+func TestUpLocked(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.sqlite")
+
+	// Concurrent callers must not fail on each other's migrations
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- UpLocked(dbPath)
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("UpLocked failed: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+	ok, current, _, err := CheckSchemaVersion(db)
+	if err != nil {
+		t.Fatalf("CheckSchemaVersion failed: %v", err)
+	}
+	if !ok || current != LatestSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", LatestSchemaVersion, current)
+	}
+}
+
+func BenchmarkCheckSchemaVersion(b *testing.B) {
+	dbPath := filepath.Join(b.TempDir(), "test.sqlite")
+	if _, _, err := Up(dbPath); err != nil {
+		b.Fatalf("Up failed: %v", err)
+	}
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		b.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			b.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := CheckSchemaVersion(db); err != nil {
+			b.Fatalf("CheckSchemaVersion failed: %v", err)
+		}
+	}
+}
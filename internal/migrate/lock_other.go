@@ -0,0 +1,9 @@
+//go:build !unix
+
+package migrate
+
+// lockFile is a no-op on platforms without flock; concurrent migrations are
+// then only serialized within a process
+func lockFile(path string) (func() error, error) {
+	return func() error { return nil }, nil
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package migrate
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile blocks until it holds an exclusive lock on path, creating the file if needed
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		if closeErr := f.Close(); closeErr != nil {
+			return nil, closeErr
+		}
+		return nil, err
+	}
+	return func() error {
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+			return err
+		}
+		return f.Close()
+	}, nil
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// upFileRegex matches the name of an up migration and captures its version
+var upFileRegex = regexp.MustCompile(`^(\d+)_.+\.up\.sql$`)
+
+// migration is an up migration read from a migrations directory
+type migration struct {
+	version int
+	name    string
+}
+
+// listMigrations returns the up migrations in dir of fsys ordered by version
+func listMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var migrations []migration
+	for _, file := range files {
+		matches := upFileRegex.FindStringSubmatch(file.Name())
+		if file.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", file.Name(), err)
+		}
+		migrations = append(migrations, migration{version: version, name: path.Join(dir, file.Name())})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// LatestVersion returns the version of the newest up migration in dir of fsys
+func LatestVersion(fsys fs.FS, dir string) (int, error) {
+	migrations, err := listMigrations(fsys, dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+// UpSQL applies the pending up migrations in dir of fsys to db, each in its own
+// transaction, and returns the resulting version. The version is recorded in a
+// schema_migrations table of the same shape golang-migrate uses, so it serves
+// backends that golang-migrate has no driver for. Callers should hold WithLock.
+func UpSQL(db *sql.DB, fsys fs.FS, dir string) (int, error) {
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL, dirty BOOLEAN NOT NULL)"); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	migrations, err := listMigrations(fsys, dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applySQL(db, fsys, m); err != nil {
+			return current, err
+		}
+		current = m.version
+	}
+	return current, nil
+}
+
+// applySQL runs one migration and records its version
+func applySQL(db *sql.DB, fsys fs.FS, m migration) error {
+	content, err := fs.ReadFile(fsys, m.name)
+	if err != nil {
+		return fmt.Errorf("failed to read migration %s: %w", m.name, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	statements := []string{
+		string(content),
+		"DELETE FROM schema_migrations",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return fmt.Errorf("failed to apply migration %s: %v, rollback failed: %v", m.name, err, rbErr)
+			}
+			return fmt.Errorf("failed to apply migration %s: %w", m.name, err)
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (?, false)", m.version); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("failed to record migration %s: %v, rollback failed: %v", m.name, err, rbErr)
+		}
+		return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestUpSQL(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create.up.sql":   {Data: []byte("CREATE TABLE items (id INTEGER)")},
+		"migrations/0001_create.down.sql": {Data: []byte("DROP TABLE items")},
+		"migrations/0002_add.up.sql":      {Data: []byte("ALTER TABLE items ADD COLUMN name TEXT")},
+		"migrations/README":               {Data: []byte("not a migration")},
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	latest, err := LatestVersion(fsys, "migrations")
+	if err != nil || latest != 2 {
+		t.Fatalf("expected latest version 2, got %d (%v)", latest, err)
+	}
+
+	// Applying twice must not run migrations again
+	for i := 0; i < 2; i++ {
+		version, err := UpSQL(db, fsys, "migrations")
+		if err != nil {
+			t.Fatalf("UpSQL failed: %v", err)
+		}
+		if version != 2 {
+			t.Errorf("expected version 2, got %d", version)
+		}
+	}
+
+	if _, err := db.Exec("INSERT INTO items (id, name) VALUES (1, 'a')"); err != nil {
+		t.Errorf("schema was not migrated: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&rows); err != nil || rows != 1 {
+		t.Errorf("expected a single version row, got %d (%v)", rows, err)
+	}
+
+	// A failing migration is rolled back and reported
+	fsys["migrations/0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE missing ADD COLUMN x TEXT")}
+	if version, err := UpSQL(db, fsys, "migrations"); err == nil || version != 2 {
+		t.Errorf("expected failure at version 2, got %d (%v)", version, err)
+	}
+}
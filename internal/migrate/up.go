@@ -0,0 +1,73 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/sett4/duckhist/internal/embedded"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Up applies all pending migrations to the database at dbPath and returns the resulting version
+func Up(dbPath string) (uint, bool, error) {
+	// Create source driver from embedded filesystem
+	sourceDriver, err := iofs.New(embedded.GetMigrationsFS(), "migrations")
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create source driver: %w", err)
+	}
+
+	// Create migration instance
+	m, err := migrate.NewWithSourceInstance("iofs", sourceDriver, fmt.Sprintf("sqlite3://%s", dbPath))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create migration instance: %w", err)
+	}
+	defer func() {
+		sourceErr, dbErr := m.Close()
+		if sourceErr != nil {
+			log.Printf("failed to close source: %v", sourceErr)
+		}
+		if dbErr != nil {
+			log.Printf("failed to close database: %v", dbErr)
+		}
+	}()
+
+	// Apply all up migrations
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return 0, false, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	// Get current version
+	version, dirty, err := m.Version()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// UpLocked applies all pending migrations like Up while holding an exclusive lock
+// on dbPath + ".lock", so that processes opening the database at the same time
+// do not migrate it concurrently
+func UpLocked(dbPath string) error {
+	return WithLock(dbPath, func() error {
+		_, _, err := Up(dbPath)
+		return err
+	})
+}
+
+// WithLock calls fn while holding an exclusive lock on dbPath + ".lock"
+func WithLock(dbPath string, fn func() error) error {
+	unlock, err := lockFile(dbPath + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock database for migration: %w", err)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			log.Printf("failed to unlock database: %v", err)
+		}
+	}()
+
+	return fn()
+}
@@ -0,0 +1,59 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dialect is the syntax of the shell that command lines are written for
+type Dialect int
+
+const (
+	// POSIX is the syntax of sh, bash, zsh, ksh and dash
+	POSIX Dialect = iota
+	// Fish is the syntax of fish, which quotes differently
+	Fish
+)
+
+// dialectNames maps shell names to their dialect
+var dialectNames = map[string]Dialect{
+	"sh":   POSIX,
+	"bash": POSIX,
+	"zsh":  POSIX,
+	"ksh":  POSIX,
+	"dash": POSIX,
+	"fish": Fish,
+}
+
+// ParseDialect returns the dialect of the shell name, which may also be a path such as "/bin/zsh"
+func ParseDialect(name string) (Dialect, error) {
+	if d, ok := dialectNames[filepath.Base(name)]; ok {
+		return d, nil
+	}
+	return POSIX, fmt.Errorf("unknown shell %q (expected sh, bash, zsh, ksh, dash or fish)", name)
+}
+
+// DetectDialect returns the dialect of the login shell in $SHELL, or POSIX if it is not known
+func DetectDialect() Dialect {
+	d, err := ParseDialect(os.Getenv("SHELL"))
+	if err != nil {
+		return POSIX
+	}
+	return d
+}
+
+// Quote quotes s so that the shell reads it back as a single word
+func (d Dialect) Quote(s string) string {
+	if d != Fish || (s != "" && !needsQuoting(s)) {
+		return Quote(s)
+	}
+	// fish also reads backslashes in single quotes as escapes
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// CdCommand returns a command line changing to dir and running command only if that succeeded
+func (d Dialect) CdCommand(dir, command string) string {
+	return "cd " + d.Quote(dir) + " && " + command
+}
@@ -0,0 +1,54 @@
+package shell
+
+import "testing"
+
+func TestParseDialect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Dialect
+	}{
+		{"zsh", POSIX},
+		{"/bin/bash", POSIX},
+		{"/usr/local/bin/fish", Fish},
+	}
+	for _, tt := range tests {
+		got, err := ParseDialect(tt.input)
+		if err != nil {
+			t.Errorf("ParseDialect(%q): unexpected error: %v", tt.input, err)
+		} else if got != tt.expected {
+			t.Errorf("ParseDialect(%q): expected %v, got %v", tt.input, tt.expected, got)
+		}
+	}
+
+	if _, err := ParseDialect("csh"); err == nil {
+		t.Error("expected an error for an unknown shell")
+	}
+}
+
+func TestDialectQuote(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		input    string
+		expected string
+	}{
+		{POSIX, "/home/user/src", "/home/user/src"},
+		{POSIX, `it's \n`, `'it'\''s \n'`},
+		{Fish, "/home/user/src", "/home/user/src"},
+		{Fish, "", "''"},
+		{Fish, `it's \n`, `'it\'s \\n'`},
+		{Fish, "$HOME (x)", "'$HOME (x)'"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.Quote(tt.input); got != tt.expected {
+			t.Errorf("Quote(%q) in dialect %v: expected %s, got %s", tt.input, tt.dialect, tt.expected, got)
+		}
+	}
+}
+
+func TestCdCommand(t *testing.T) {
+	got := POSIX.CdCommand("/tmp/my dir; rm -rf ~", "make test")
+	expected := "cd '/tmp/my dir; rm -rf ~' && make test"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,27 @@
+package shell
+
+import "strings"
+
+// Quote quotes s so that a POSIX-compatible shell reads it back as a single word
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !needsQuoting(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// needsQuoting reports whether s contains characters that are special to the shell
+func needsQuoting(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-_./:,+@%=", r):
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package shell
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "''"},
+		{"/home/user/src", "/home/user/src"},
+		{"git status", "'git status'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a;b", "'a;b'"},
+		{"~/work", "'~/work'"},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.input); got != tt.expected {
+			t.Errorf("Quote(%q): expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+}
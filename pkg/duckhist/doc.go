@@ -0,0 +1,24 @@
+// Package duckhist provides access to a duckhist history database for programs
+// embedding it, such as bots or dashboards built on shell history.
+//
+// A Store is opened on the database file used by the duckhist command. Open
+// creates the database and applies schema migrations as needed, so a program
+// and the command can share the same file. Only databases of the default SQLite
+// storage backend can be opened:
+//
+//	store, err := duckhist.Open(path)
+//	if err != nil {
+//		return err
+//	}
+//	defer store.Close()
+//
+//	entries, err := store.Query(ctx, duckhist.Filter{Directory: "/home/user/project", Limit: 10})
+//
+// # Compatibility
+//
+// This package follows semantic versioning of the duckhist module. Within a
+// major version, exported identifiers are not removed or changed in an
+// incompatible way; new functions, methods, and struct fields may be added.
+// Struct types should therefore be initialized with field names.
+// Packages under internal/ are not covered by this guarantee.
+package duckhist
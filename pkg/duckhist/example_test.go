@@ -0,0 +1,100 @@
+package duckhist_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sett4/duckhist/pkg/duckhist"
+)
+
+func Example() {
+	dir, err := os.MkdirTemp("", "duckhist-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("failed to remove directory: %v", err)
+		}
+	}()
+
+	store, err := duckhist.Open(filepath.Join(dir, "history.db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			log.Printf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	for _, command := range []string{"make", "make test", "git push", "make"} {
+		duplicate, err := store.Add(ctx, duckhist.Entry{Command: command, Directory: "/src/app", Hostname: "laptop"})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if duplicate {
+			fmt.Printf("skipped duplicate %q\n", command)
+		}
+	}
+
+	entries, err := store.Query(ctx, duckhist.Filter{Prefix: "make", OldestFirst: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, entry := range entries {
+		fmt.Println(entry.Command)
+	}
+	// Output:
+	// skipped duplicate "make"
+	// make
+	// make test
+}
+
+func ExampleStore_Subscribe() {
+	dir, err := os.MkdirTemp("", "duckhist-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("failed to remove directory: %v", err)
+		}
+	}()
+
+	store, err := duckhist.Open(filepath.Join(dir, "history.db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			log.Printf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Commands recorded by any process, e.g. the shell hook, are delivered
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if _, err := store.Add(ctx, duckhist.Entry{Command: "deploy production", Directory: "/src/app"}); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	err = store.Subscribe(ctx, duckhist.Filter{Search: "deploy"}, 10*time.Millisecond, func(entry duckhist.Entry) error {
+		fmt.Println("new command:", entry.Command)
+		cancel()
+		return nil
+	})
+	fmt.Println(err)
+	// Output:
+	// new command: deploy production
+	// context canceled
+}
@@ -0,0 +1,257 @@
+package duckhist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/sett4/duckhist/internal/history"
+	"github.com/sett4/duckhist/internal/migrate"
+)
+
+// DefaultPollInterval is the interval at which Subscribe checks for new entries
+// when no interval is given
+const DefaultPollInterval = time.Second
+
+// subscribeOverlap is how far back Subscribe looks on every poll. IDs are
+// generated before an entry is written, so an entry committed late, e.g. by
+// a long import transaction, can have an older ID than entries already seen.
+const subscribeOverlap = time.Minute
+
+// Entry is a command recorded in history
+type Entry struct {
+	// ID identifies the entry. IDs sort in the order entries were recorded.
+	ID        string
+	Command   string
+	Time      time.Time
+	Hostname  string
+	Directory string
+	Username  string
+	TTY       string
+	SessionID string
+}
+
+// Filter selects entries. The zero value selects all entries, newest first.
+type Filter struct {
+	// Directory selects entries run in this directory
+	Directory string
+	// Recursive also selects entries run in subdirectories of Directory
+	Recursive bool
+	Hostname  string
+	SessionID string
+	TTY       string
+	// Prefix selects commands starting with this string
+	Prefix string
+	// Search selects commands containing all keywords or "quoted phrases", ignoring case
+	Search string
+	// AfterID selects entries recorded after the entry with this ID
+	AfterID string
+	// Limit is the maximum number of entries returned; 0 means no limit
+	Limit int
+	// OldestFirst returns entries in the order they were recorded
+	OldestFirst bool
+}
+
+// Store is a duckhist history database. It is safe for concurrent use.
+type Store struct {
+	manager *history.Manager
+}
+
+// Open opens the history database at path for reading and writing.
+// The database is created if it does not exist, and pending schema migrations
+// are applied while holding a lock, so that concurrent processes do not
+// migrate the same database twice.
+// Only the default SQLite storage backend is supported; the storage_backend
+// setting of the duckhist configuration is not consulted.
+func Open(path string) (*Store, error) {
+	if err := migrate.UpLocked(path); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	manager, err := history.NewManagerReadWrite(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &Store{manager: manager}, nil
+}
+
+// OpenReadOnly opens an existing SQLite history database at path for reading only.
+// No migrations are applied.
+func OpenReadOnly(path string) (*Store, error) {
+	manager, err := history.NewManagerReadOnly(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &Store{manager: manager}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.manager.Close()
+}
+
+// Add records a command and reports whether it was skipped because the same
+// command was already recorded in the same directory, host, and user, in any
+// session, like commands recorded by the shell hook with the default
+// configuration. The ID of e is ignored. A zero Time is replaced with the
+// current time.
+func (s *Store) Add(ctx context.Context, e Entry) (bool, error) {
+	return s.add(ctx, e, false)
+}
+
+// AddNoDedup is like Add but records the command even if it is a duplicate,
+// like the --no-dedup flag of the add command
+func (s *Store) AddNoDedup(ctx context.Context, e Entry) error {
+	_, err := s.add(ctx, e, true)
+	return err
+}
+
+func (s *Store) add(ctx context.Context, e Entry, noDedup bool) (bool, error) {
+	if e.Command == "" {
+		return false, errors.New("command is empty")
+	}
+	if e.Directory == "" {
+		return false, errors.New("directory is empty")
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return s.manager.AddCommandContext(ctx, e.Command, e.Directory, e.TTY, e.SessionID, e.Hostname, e.Username, e.Time, noDedup)
+}
+
+// Query returns the entries selected by f
+func (s *Store) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	var entries []Entry
+	err := s.Each(ctx, f, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Each calls fn for every entry selected by f as it is read from the database.
+// Iteration stops at the first error returned by fn, which is then returned by Each.
+func (s *Store) Each(ctx context.Context, f Filter, fn func(Entry) error) error {
+	return s.query(f).EachContext(ctx, func(entry history.Entry) error {
+		return fn(fromHistory(entry))
+	})
+}
+
+// Subscribe calls fn once for every entry selected by f that is recorded after
+// Subscribe was called, including entries written by other processes. Entries
+// are passed in the order they were recorded, except that an entry committed
+// late by a slow writer is passed when it becomes visible, up to a minute after
+// its ID was generated. The database is polled every interval, or every
+// DefaultPollInterval if interval is not positive; f.AfterID, f.Limit and
+// f.OldestFirst are ignored.
+// Subscribe blocks until ctx is canceled, returning ctx.Err(), or until fn
+// returns an error, returning that error.
+func (s *Store) Subscribe(ctx context.Context, f Filter, interval time.Duration, fn func(Entry) error) error {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	f.Limit = 0
+	f.OldestFirst = true
+
+	// Every poll scans the entries of the overlap window again and skips the
+	// ones already seen, starting with those recorded before Subscribe was called
+	seen := make(map[string]bool)
+	f.AfterID = idBefore(time.Now().Add(-subscribeOverlap))
+	err := s.Each(ctx, f, func(e Entry) error {
+		seen[e.ID] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		f.AfterID = idBefore(time.Now().Add(-subscribeOverlap))
+		for id := range seen {
+			if id <= f.AfterID {
+				delete(seen, id)
+			}
+		}
+		err := s.Each(ctx, f, func(e Entry) error {
+			if seen[e.ID] {
+				return nil
+			}
+			seen[e.ID] = true
+			return fn(e)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// query builds the internal query for f
+func (s *Store) query(f Filter) *history.HistoryQuery {
+	q := s.manager.Query()
+	if f.Directory != "" {
+		if f.Recursive {
+			q.UnderDirectory(f.Directory)
+		} else {
+			q.InDirectory(f.Directory)
+		}
+	}
+	if f.Hostname != "" {
+		q.OnHost(f.Hostname)
+	}
+	if f.SessionID != "" {
+		q.InSession(f.SessionID)
+	}
+	if f.TTY != "" {
+		q.OnTTY(f.TTY)
+	}
+	if f.Prefix != "" {
+		q.WithPrefix(f.Prefix)
+	}
+	if f.Search != "" {
+		q.Search(f.Search)
+	}
+	if f.AfterID != "" {
+		q.After(f.AfterID)
+	}
+	if f.Limit > 0 {
+		q.Limit(f.Limit)
+	}
+	if f.OldestFirst {
+		q.OrderByOldestFirst()
+	}
+	return q
+}
+
+// idBefore returns an ID sorting before the IDs of all entries recorded at t or later
+func idBefore(t time.Time) string {
+	var id ulid.ULID
+	if err := id.SetTime(ulid.Timestamp(t)); err != nil {
+		return ""
+	}
+	return id.String()
+}
+
+func fromHistory(entry history.Entry) Entry {
+	return Entry{
+		ID:        entry.ID,
+		Command:   entry.Command,
+		Time:      entry.Timestamp,
+		Hostname:  entry.Hostname,
+		Directory: entry.Directory,
+		Username:  entry.Username,
+		TTY:       entry.TTY,
+		SessionID: entry.SID,
+	}
+}
@@ -0,0 +1,199 @@
+package duckhist
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	records := []Entry{
+		{Command: "ls", Directory: "/src", Hostname: "a", SessionID: "s1"},
+		{Command: "make", Directory: "/src/app", Hostname: "a", SessionID: "s1"},
+		{Command: "ls", Directory: "/tmp", Hostname: "b", SessionID: "s2"},
+	}
+	for _, e := range records {
+		if _, err := store.Add(ctx, e); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if _, err := store.Add(ctx, Entry{Command: "ls"}); err == nil {
+		t.Error("expected error without directory")
+	}
+	// Duplicates are skipped in any session, unless added with AddNoDedup
+	if dup, err := store.Add(ctx, Entry{Command: "ls", Directory: "/src", Hostname: "a", SessionID: "s3"}); err != nil || !dup {
+		t.Errorf("expected duplicate, got %v, %v", dup, err)
+	}
+	if err := store.AddNoDedup(ctx, Entry{Command: "pwd"}); err == nil {
+		t.Error("expected error without directory")
+	}
+
+	all, err := store.Query(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(all) != 3 || all[0].Directory != "/tmp" || all[0].Time.IsZero() {
+		t.Fatalf("unexpected entries: %+v", all)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		count  int
+	}{
+		{"directory", Filter{Directory: "/src"}, 1},
+		{"recursive", Filter{Directory: "/src", Recursive: true}, 2},
+		{"host", Filter{Hostname: "b"}, 1},
+		{"session", Filter{SessionID: "s1"}, 2},
+		{"search", Filter{Search: "LS"}, 2},
+		{"after", Filter{AfterID: all[2].ID}, 2},
+		{"limit", Filter{Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := store.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(entries) != tt.count {
+				t.Errorf("expected %d entries, got %+v", tt.count, entries)
+			}
+		})
+	}
+
+	t.Run("read only", func(t *testing.T) {
+		reader, err := OpenReadOnly(path)
+		if err != nil {
+			t.Fatalf("OpenReadOnly failed: %v", err)
+		}
+		defer func() {
+			if err := reader.Close(); err != nil {
+				t.Errorf("failed to close store: %v", err)
+			}
+		}()
+
+		entries, err := reader.Query(ctx, Filter{OldestFirst: true})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(entries) != 3 || entries[0].Command != "ls" || entries[0].Directory != "/src" {
+			t.Errorf("unexpected entries: %+v", entries)
+		}
+		if _, err := reader.Add(ctx, Entry{Command: "pwd", Directory: "/"}); err == nil {
+			t.Error("expected error when adding to a read-only store")
+		}
+	})
+
+	t.Run("subscribe", func(t *testing.T) {
+		subCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			for _, command := range []string{"echo 1", "pwd", "echo 2"} {
+				if _, err := store.Add(ctx, Entry{Command: command, Directory: "/src"}); err != nil {
+					t.Errorf("Add failed: %v", err)
+				}
+			}
+		}()
+
+		var received []string
+		err := store.Subscribe(subCtx, Filter{Prefix: "echo"}, 10*time.Millisecond, func(e Entry) error {
+			received = append(received, e.Command)
+			if len(received) == 2 {
+				cancel()
+			}
+			return nil
+		})
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if len(received) != 2 || received[0] != "echo 1" || received[1] != "echo 2" {
+			t.Errorf("unexpected entries: %v", received)
+		}
+	})
+
+	t.Run("subscribe late commit", func(t *testing.T) {
+		subCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		db, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				t.Errorf("failed to close database: %v", err)
+			}
+		}()
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			if _, err := store.Add(ctx, Entry{Command: "late 1", Directory: "/src"}); err != nil {
+				t.Errorf("Add failed: %v", err)
+			}
+		}()
+
+		var received []string
+		err = store.Subscribe(subCtx, Filter{Prefix: "late"}, 10*time.Millisecond, func(e Entry) error {
+			received = append(received, e.Command)
+			if len(received) == 1 {
+				// A writer that generated its ID before the entry already seen commits now
+				id := ulid.MustNew(ulid.Timestamp(time.Now().Add(-10*time.Second)), ulid.DefaultEntropy()).String()
+				_, err := db.Exec(`INSERT INTO history (id, command, executed_at, executing_host, executing_dir, executing_user)
+					VALUES (?, 'late 0', ?, '', '/src', '')`, id, time.Now())
+				return err
+			}
+			cancel()
+			return nil
+		})
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if len(received) != 2 || received[0] != "late 1" || received[1] != "late 0" {
+			t.Errorf("unexpected entries: %v", received)
+		}
+	})
+}
+
+func TestStoreAddNoDedup(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("failed to close store: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := store.AddNoDedup(ctx, Entry{Command: "make", Directory: "/src"}); err != nil {
+			t.Fatalf("AddNoDedup failed: %v", err)
+		}
+	}
+	entries, err := store.Query(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected both commands to be recorded, got %+v", entries)
+	}
+}